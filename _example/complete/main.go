@@ -5,7 +5,7 @@ import (
 	"context"
 	"example/metrics/data"
 	"fmt"
-	"github.com/rs/zerolog"
+	"github.com/twistingmercury/telemetry/v2"
 	"github.com/twistingmercury/telemetry/v2/logging"
 	"github.com/twistingmercury/telemetry/v2/metrics"
 	"github.com/twistingmercury/telemetry/v2/tracing"
@@ -25,43 +25,39 @@ const (
 )
 
 func main() {
-	// 1. initialize logging
-	//    from this point forward use the logging package to log messages
-	if err := logging.Initialize(zerolog.DebugLevel, os.Stdout, serviceName, version, environment); err != nil {
-		log.Fatalf("failed to initialize old_elemetry: %s", err)
-	}
-
-	// 3. initialize tracing
 	tex, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
 	if err != nil {
-		// fail fast!
-		logging.Fatal(err, "failed to create trace exporter")
-	}
-
-	if err := tracing.Initialize(tex, serviceName, version, environment); err != nil {
-		logging.Fatal(err, "failed to initialize tracing")
+		log.Fatalf("failed to create trace exporter: %s", err)
 	}
 
-	// 2. initialize metrics
-	if err := metrics.Initialize(namespace, serviceName); err != nil {
-		log.Fatalf("failed to initialize metrics: %s", err)
+	// Setup initializes logging, tracing and metrics, and publishes the metrics.
+	// From this point forward use the logging package to log messages.
+	tel, err := telemetry.Setup(context.Background(), telemetry.Config{
+		ServiceName:      serviceName,
+		ServiceVersion:   version,
+		Environment:      environment,
+		LogLevel:         "debug",
+		LogWriter:        os.Stdout,
+		TraceExporter:    tex,
+		MetricsNamespace: namespace,
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize telemetry: %s", err)
 	}
+	// flushes the spans, stops the metrics server and flushes the logs
+	defer func() { _ = tel.Shutdown(context.Background()) }()
 
 	metrics.RegisterMetrics(data.Metrics()...)
 
-	metrics.Publish()
-
 	for i := 0; i < 5; i++ {
 		_ = data.DoDatabaseStuff()
 		time.Sleep(250 * time.Millisecond)
 	}
 
-	stopChan := make(chan struct{})
-	defer close(stopChan)
-	echo(stopChan)
+	echo()
 }
 
-func echo(stopChan chan struct{}) {
+func echo() {
 	// Make sure to start the span before logging so that the span context is available. This is important
 	// for the trace to be able to corrolate the logs to the trace.
 	_, span := tracing.Start(context.Background(), "echo", trace.SpanKindServer)
@@ -74,6 +70,4 @@ func echo(stopChan chan struct{}) {
 
 	span.SetStatus(codes.Ok, "OK")
 	span.End()
-
-	<-stopChan // this is just to make it wait so that the telemetry is sent to stdout
 }
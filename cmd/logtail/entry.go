@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/twistingmercury/telemetry/v2/logging"
+)
+
+// The fields written by the logging package that are shown in their own column, or not at all.
+const (
+	serviceField     = "service"
+	versionField     = "version"
+	environmentField = "environment"
+	fatalField       = "is-fatal"
+)
+
+// entry is a line read from the input. Lines that are not JSON objects only have raw set.
+type entry struct {
+	raw     string
+	fields  map[string]any
+	time    time.Time
+	level   zerolog.Level
+	message string
+	service string
+	traceID string
+	spanID  string
+}
+
+// parseEntry parses a line written by the logging package.
+func parseEntry(line string) entry {
+	e := entry{raw: line, level: zerolog.NoLevel}
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		return e
+	}
+
+	dec := json.NewDecoder(strings.NewReader(trimmed))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return e
+	}
+
+	e.fields = fields
+	e.message = stringField(fields, zerolog.MessageFieldName)
+	e.service = stringField(fields, serviceField)
+	e.traceID = stringField(fields, logging.TraceIDAttr)
+	e.spanID = stringField(fields, logging.SpanIDAttr)
+	if ts := stringField(fields, zerolog.TimestampFieldName); ts != "" {
+		e.time, _ = time.Parse(time.RFC3339Nano, ts)
+	}
+	if lvl, err := zerolog.ParseLevel(stringField(fields, zerolog.LevelFieldName)); err == nil {
+		e.level = lvl
+	}
+	// The logging package writes fatal messages at the error level, marked with is-fatal.
+	if stringField(fields, fatalField) == "true" {
+		e.level = zerolog.FatalLevel
+	}
+	return e
+}
+
+// isJSON reports whether the line was a JSON object.
+func (e entry) isJSON() bool {
+	return e.fields != nil
+}
+
+func stringField(fields map[string]any, key string) string {
+	if v, ok := fields[key]; ok {
+		return formatValue(v)
+	}
+	return ""
+}
+
+// formatValue returns a field value as text: strings and numbers as they are, and other values as JSON.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return "null"
+	default:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return fmt.Sprint(v)
+		}
+		return strings.TrimSuffix(buf.String(), "\n")
+	}
+}
+
+// operators are the operators of a field expression. The two character operators come first, so `!=` is not read
+// as `!` followed by `=`.
+var operators = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+// expr is a field expression, such as `status>=500`, `user.id=42` or `path~^/api/`. An expression without an
+// operator matches the lines that have the field.
+type expr struct {
+	key   string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+// parseExpr parses a field expression.
+func parseExpr(s string) (expr, error) {
+	for i := 0; i < len(s); i++ {
+		for _, op := range operators {
+			if !strings.HasPrefix(s[i:], op) {
+				continue
+			}
+			e := expr{key: strings.TrimSpace(s[:i]), op: op, value: s[i+len(op):]}
+			if e.key == "" {
+				return expr{}, fmt.Errorf("invalid expression: `%s`; the field name is missing", s)
+			}
+			if op == "~" || op == "!~" {
+				re, err := regexp.Compile(e.value)
+				if err != nil {
+					return expr{}, fmt.Errorf("invalid expression: `%s`: %w", s, err)
+				}
+				e.re = re
+			}
+			return e, nil
+		}
+	}
+	if strings.TrimSpace(s) == "" {
+		return expr{}, fmt.Errorf("invalid expression: `%s`; the field name is missing", s)
+	}
+	return expr{key: strings.TrimSpace(s)}, nil
+}
+
+// matches reports whether the line matches the expression. A line without the field only matches `!=` and `!~`.
+func (x expr) matches(e entry) bool {
+	v, ok := e.fields[x.key]
+	if x.op == "" {
+		return ok
+	}
+	if !ok {
+		return x.op == "!=" || x.op == "!~"
+	}
+
+	value := formatValue(v)
+	switch x.op {
+	case "=":
+		return value == x.value
+	case "!=":
+		return value != x.value
+	case "~":
+		return x.re.MatchString(value)
+	case "!~":
+		return !x.re.MatchString(value)
+	}
+
+	// The comparisons are numeric when both sides are numbers, and compare text otherwise.
+	cmp := strings.Compare(value, x.value)
+	a, errA := strconv.ParseFloat(value, 64)
+	b, errB := strconv.ParseFloat(x.value, 64)
+	if errA == nil && errB == nil {
+		cmp = compareFloats(a, b)
+	}
+	switch x.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// filter selects the lines that are printed.
+type filter struct {
+	// level is the minimum level printed; zerolog.TraceLevel prints every line.
+	level   zerolog.Level
+	service string
+	traceID string
+	exprs   []expr
+}
+
+// active reports whether f filters anything. Lines that are not JSON are only printed when it does not.
+func (f filter) active() bool {
+	return f.level > zerolog.TraceLevel || f.service != "" || f.traceID != "" || len(f.exprs) > 0
+}
+
+// matches reports whether the line matches every condition of f.
+func (f filter) matches(e entry) bool {
+	if !e.isJSON() {
+		return !f.active()
+	}
+	if f.level > zerolog.TraceLevel && (e.level < f.level || e.level == zerolog.NoLevel) {
+		return false
+	}
+	if f.service != "" && e.service != f.service {
+		return false
+	}
+	if f.traceID != "" && e.traceID != f.traceID {
+		return false
+	}
+	for _, x := range f.exprs {
+		if !x.matches(e) {
+			return false
+		}
+	}
+	return true
+}
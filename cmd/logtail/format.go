@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/twistingmercury/telemetry/v2/internal/spanfile"
+	"github.com/twistingmercury/telemetry/v2/logging"
+)
+
+// timeFormat is the format of the time column.
+const timeFormat = "15:04:05.000"
+
+// The ANSI escape codes used to colour the output.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+)
+
+// levelColors are the colours of the level column.
+var levelColors = map[zerolog.Level]string{
+	zerolog.TraceLevel: ansiDim,
+	zerolog.DebugLevel: ansiBlue,
+	zerolog.InfoLevel:  ansiGreen,
+	zerolog.WarnLevel:  ansiYellow,
+	zerolog.ErrorLevel: ansiRed,
+	zerolog.FatalLevel: ansiBold + ansiMagenta,
+	zerolog.PanicLevel: ansiBold + ansiMagenta,
+}
+
+// hiddenFields are not printed with the other fields, because they have their own column or are the same on every
+// line of a service.
+var hiddenFields = map[string]bool{
+	zerolog.TimestampFieldName: true,
+	zerolog.LevelFieldName:     true,
+	zerolog.MessageFieldName:   true,
+	serviceField:               true,
+	versionField:               true,
+	environmentField:           true,
+	fatalField:                 true,
+	logging.TraceIDAttr:        true,
+	logging.SpanIDAttr:         true,
+}
+
+// formatter writes the lines in a human-readable form:
+//
+//	15:04:05.000 INFO  checkout  order placed  order.id=42 span="POST /orders" trace=0af7651916cd43dd8448eb211c80319c
+type formatter struct {
+	w     io.Writer
+	color bool
+	// spans are the spans read from the span files, by span id, used to name the span of each line.
+	spans map[string]*spanfile.Span
+	// hideTrace omits the trace id, when the lines are grouped by trace.
+	hideTrace bool
+}
+
+// print writes a line. Lines that are not JSON are written as they are.
+func (f formatter) print(e entry) {
+	if !e.isJSON() {
+		fmt.Fprintln(f.w, e.raw)
+		return
+	}
+
+	var b strings.Builder
+	if !e.time.IsZero() {
+		b.WriteString(f.paint(ansiDim, e.time.Format(timeFormat)))
+		b.WriteByte(' ')
+	}
+	b.WriteString(f.paint(levelColors[e.level], fmt.Sprintf("%-5s", levelName(e.level))))
+	if e.service != "" {
+		b.WriteString(" " + f.paint(ansiCyan, e.service))
+	}
+	b.WriteString("  " + f.paint(ansiBold, e.message))
+
+	keys := make([]string, 0, len(e.fields))
+	for k := range e.fields {
+		if !hiddenFields[k] {
+			keys = append(keys, k)
+		}
+	}
+	// The error comes first, because it is the field looked for on a failure.
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i] == zerolog.ErrorFieldName) != (keys[j] == zerolog.ErrorFieldName) {
+			return keys[i] == zerolog.ErrorFieldName
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > 0 {
+		b.WriteByte(' ')
+	}
+	for _, k := range keys {
+		value := quote(formatValue(e.fields[k]))
+		if k == zerolog.ErrorFieldName {
+			value = f.paint(ansiRed, value)
+		}
+		b.WriteString(" " + f.paint(ansiDim, k+"=") + value)
+	}
+
+	if e.spanID != "" {
+		name := e.spanID
+		if s, ok := f.spans[e.spanID]; ok {
+			name = quote(s.Name)
+		}
+		b.WriteString(" " + f.paint(ansiDim, "span=") + name)
+	}
+	if e.traceID != "" && !f.hideTrace {
+		b.WriteString(" " + f.paint(ansiDim, "trace=") + e.traceID)
+	}
+	fmt.Fprintln(f.w, b.String())
+}
+
+// header writes the first line of the group of lines of a trace, with its root span when it is in the span files.
+func (f formatter) header(traceID string, root *spanfile.Span, lines int) {
+	if traceID == "" {
+		fmt.Fprintln(f.w, f.paint(ansiBold, fmt.Sprintf("── no trace  %d lines", lines)))
+		return
+	}
+
+	title := "── trace " + traceID
+	status := ""
+	if root != nil {
+		title += "  " + root.Name
+		if root.Service != "" {
+			title += " [" + root.Service + "]"
+		}
+		title += "  " + root.Duration().Round(time.Microsecond).String()
+		if root.Error {
+			status = "  " + f.paint(ansiRed, "ERROR")
+		}
+	}
+	fmt.Fprintf(f.w, "%s%s  %d lines\n", f.paint(ansiBold, title), status, lines)
+}
+
+// paint colours s with the escape code, when colours are enabled.
+func (f formatter) paint(code, s string) string {
+	if !f.color || code == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// levelName returns the upper-case name of the level.
+func levelName(l zerolog.Level) string {
+	if l == zerolog.NoLevel {
+		return "-"
+	}
+	return strings.ToUpper(l.String())
+}
+
+// quote quotes s when it is empty or has spaces, so every field is a single word.
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
@@ -0,0 +1,269 @@
+// Command logtail pretty-prints the JSON lines written by the logging package, coloured by level, and correlates
+// them with traces.
+//
+// Usage:
+//
+//	logtail [flags] [file ...]
+//
+// With no file, or the file `-`, the lines are read from stdin, so it can follow a running service:
+//
+//	go run ./cmd/service | logtail -level warn
+//
+// Lines are printed as they are read, unless -group is set: the whole input is then read, and the lines of each
+// trace are printed together, ordered by time. The lines can be filtered with:
+//
+//	-level level       lines at the level or above, such as warn
+//	-service name      lines of the service
+//	-trace id          lines of the trace
+//	-where expr        lines matching a field expression; can be repeated
+//
+// A field expression is a field name, an operator and a value, such as `http.status_code>=500`, `user.id=42` or
+// `path~^/api/`. The operators are = and != (equal), ~ and !~ (regular expression), and >, >=, < and <=, which compare
+// numbers when both sides are numbers. A field name alone matches the lines that have the field. Lines that are not
+// JSON are printed as they are, unless a filter is set.
+//
+// -spans reads span files, in any format read by traceview, to show the name of the span each line was logged in,
+// and the root span of each trace when the lines are grouped. -color is auto, always or never; auto colours the
+// output when it is a terminal and the NO_COLOR environment variable is not set.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/twistingmercury/telemetry/v2/internal/spanfile"
+)
+
+// maxLineSize is the longest line read from a log file.
+const maxLineSize = 16 << 20
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// stringList is a flag that can be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// run runs the command and returns its exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("logtail", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: logtail [flags] [file ...]")
+		fs.PrintDefaults()
+	}
+
+	var (
+		where     stringList
+		spanFiles stringList
+		f         = filter{level: zerolog.TraceLevel}
+	)
+	level := fs.String("level", "", "only show lines at this `level` or above, such as warn")
+	fs.StringVar(&f.service, "service", "", "only show lines of this `service`")
+	fs.StringVar(&f.traceID, "trace", "", "only show lines of this trace `id`")
+	fs.Var(&where, "where", "only show lines matching the field `expression`, such as status>=500; can be repeated")
+	fs.Var(&spanFiles, "spans", "read the spans in the `file` to name the span of each line; can be repeated")
+	group := fs.Bool("group", false, "read the whole input, and print the lines of each trace together")
+	colorMode := fs.String("color", "auto", "colour the output: auto, always or never")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := f.parse(*level, where); err != nil {
+		fmt.Fprintf(stderr, "logtail: %s\n", err)
+		return 2
+	}
+	color, err := useColor(*colorMode, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "logtail: %s\n", err)
+		return 2
+	}
+
+	spans, err := readSpanFiles(spanFiles)
+	if err != nil {
+		fmt.Fprintf(stderr, "logtail: %s\n", err)
+		return 1
+	}
+	out := formatter{w: stdout, color: color, spans: make(map[string]*spanfile.Span, len(spans)), hideTrace: *group}
+	for _, s := range spans {
+		out.spans[s.SpanID] = s
+	}
+
+	var entries []entry
+	err = readInputs(fs.Args(), stdin, func(e entry) {
+		if !f.matches(e) {
+			return
+		}
+		if *group {
+			entries = append(entries, e)
+		} else {
+			out.print(e)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "logtail: %s\n", err)
+		return 1
+	}
+	if *group {
+		printGroups(out, entries, spans)
+	}
+	return 0
+}
+
+// parse sets the level and the field expressions of f from the flags.
+func (f *filter) parse(level string, where []string) error {
+	if level != "" {
+		lvl, err := zerolog.ParseLevel(strings.ToLower(level))
+		if err != nil || lvl == zerolog.NoLevel {
+			return fmt.Errorf("invalid level: `%s`", level)
+		}
+		f.level = lvl
+	}
+	for _, w := range where {
+		x, err := parseExpr(w)
+		if err != nil {
+			return err
+		}
+		f.exprs = append(f.exprs, x)
+	}
+	return nil
+}
+
+// useColor reports whether the output is coloured.
+func useColor(mode string, stdout io.Writer) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		file, ok := stdout.(*os.File)
+		if !ok {
+			return false, nil
+		}
+		info, err := file.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("invalid color: `%s`; it must be auto, always or never", mode)
+	}
+}
+
+// readSpanFiles reads the spans in the span files.
+func readSpanFiles(names []string) ([]*spanfile.Span, error) {
+	var spans []*spanfile.Span
+	for _, name := range names {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		read, err := spanfile.Read(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read `%s`: %w", name, err)
+		}
+		spans = append(spans, read...)
+	}
+	return spans, nil
+}
+
+// readInputs calls fn with every line of the files, or of stdin when there is none.
+func readInputs(names []string, stdin io.Reader, fn func(entry)) error {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	for _, name := range names {
+		if err := readInput(name, stdin, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readInput(name string, stdin io.Reader, fn func(entry)) error {
+	r := stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+		r = file
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		fn(parseEntry(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("a line of `%s` is longer than %d bytes", name, maxLineSize)
+		}
+		return err
+	}
+	return nil
+}
+
+// printGroups prints the lines of each trace together, ordered by time, in the order the traces were first logged.
+// The lines without a trace id are printed last.
+func printGroups(out formatter, entries []entry, spans []*spanfile.Span) {
+	var order []string
+	groups := make(map[string][]entry)
+	for _, e := range entries {
+		if _, ok := groups[e.traceID]; !ok && e.traceID != "" {
+			order = append(order, e.traceID)
+		}
+		groups[e.traceID] = append(groups[e.traceID], e)
+	}
+	if _, ok := groups[""]; ok {
+		order = append(order, "")
+	}
+
+	roots := rootSpans(spans)
+	for _, id := range order {
+		lines := groups[id]
+		sort.SliceStable(lines, func(i, j int) bool { return lines[i].time.Before(lines[j].time) })
+		out.header(id, roots[id], len(lines))
+		for _, e := range lines {
+			out.print(e)
+		}
+		fmt.Fprintln(out.w)
+	}
+}
+
+// rootSpans returns the earliest span of each trace without a parent in spans, by trace id.
+func rootSpans(spans []*spanfile.Span) map[string]*spanfile.Span {
+	ids := make(map[string]bool, len(spans))
+	for _, s := range spans {
+		ids[s.SpanID] = true
+	}
+	roots := make(map[string]*spanfile.Span)
+	for _, s := range spans {
+		if s.ParentID != "" && ids[s.ParentID] {
+			continue
+		}
+		if r, ok := roots[s.TraceID]; !ok || s.Start.Before(r.Start) {
+			roots[s.TraceID] = s
+		}
+	}
+	return roots
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// recordLogs logs a request of the checkout service, traced in a span file, and a line of the payments service
+// without a trace. It returns the log lines, the path of the span file and the trace id of the request.
+func recordLogs(t *testing.T) (logs, spans, traceID string) {
+	spans = filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := tracing.NewFileExporter(tracing.FileExporterOptions{Path: spans})
+	require.NoError(t, err)
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "checkout"))),
+	)
+	tracer := tp.Tracer("logtail-test")
+
+	var buf bytes.Buffer
+	checkout, err := logging.New(logging.Options{Level: zerolog.DebugLevel, Writer: &buf, ServiceName: "checkout"})
+	require.NoError(t, err)
+	payments, err := logging.New(logging.Options{Level: zerolog.DebugLevel, Writer: &buf, ServiceName: "payments"})
+	require.NoError(t, err)
+
+	ctx, root := tracer.Start(context.Background(), "POST /orders")
+	checkout.Info(ctx, "order received", logging.KeyValue{Key: "order.id", Value: 42})
+	queryCtx, query := tracer.Start(ctx, "INSERT orders")
+	checkout.Error(queryCtx, errors.New("deadlock"), "query failed", logging.KeyValue{Key: "http.status_code", Value: 503})
+	query.End()
+	root.End()
+	payments.Debug(context.Background(), "card authorized", logging.KeyValue{Key: "amount", Value: 9.5})
+
+	require.NoError(t, tp.Shutdown(context.Background()))
+	return "starting\n" + buf.String(), spans, root.SpanContext().TraceID().String()
+}
+
+func TestRun(t *testing.T) {
+	logs, _, traceID := recordLogs(t)
+
+	var out, errOut bytes.Buffer
+	require.Equal(t, 0, run([]string{"-color", "never"}, strings.NewReader(logs), &out, &errOut), errOut.String())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "starting", lines[0], "lines that are not JSON should be printed as they are")
+	assert.Regexp(t, `^\d\d:\d\d:\d\d\.\d{3} INFO  checkout  order received  order\.id=42 span=[0-9a-f]{16} trace=`+traceID+`$`, lines[1])
+	assert.Regexp(t, `^\S+ ERROR checkout  query failed  error=deadlock http\.status_code=503 span=`, lines[2], "the error should be the first field")
+	assert.Regexp(t, `^\S+ DEBUG payments  card authorized  amount=9\.5$`, lines[3])
+}
+
+func TestRunColor(t *testing.T) {
+	logs, _, _ := recordLogs(t)
+
+	var out, errOut bytes.Buffer
+	require.Equal(t, 0, run([]string{"-color", "always"}, strings.NewReader(logs), &out, &errOut))
+	assert.Contains(t, out.String(), ansiGreen+"INFO "+ansiReset)
+	assert.Contains(t, out.String(), ansiRed+"ERROR"+ansiReset)
+	assert.Contains(t, out.String(), ansiRed+"deadlock"+ansiReset)
+
+	out.Reset()
+	require.Equal(t, 0, run(nil, strings.NewReader(logs), &out, &errOut))
+	assert.NotContains(t, out.String(), "\x1b[", "the output should not be coloured when it is not a terminal")
+}
+
+func TestRunFilters(t *testing.T) {
+	logs, _, traceID := recordLogs(t)
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"level", []string{"-level", "info"}, []string{"order received", "query failed"}},
+		{"level upper case", []string{"-level", "ERROR"}, []string{"query failed"}},
+		{"service", []string{"-service", "payments"}, []string{"card authorized"}},
+		{"trace", []string{"-trace", traceID}, []string{"order received", "query failed"}},
+		{"equal", []string{"-where", "order.id=42"}, []string{"order received"}},
+		{"not equal", []string{"-where", "order.id!=42"}, []string{"query failed", "card authorized"}},
+		{"numeric comparison", []string{"-where", "http.status_code>=500"}, []string{"query failed"}},
+		{"fractional comparison", []string{"-where", "amount<10"}, []string{"card authorized"}},
+		{"regular expression", []string{"-where", "message~^(order|card)"}, []string{"order received", "card authorized"}},
+		{"not regular expression", []string{"-where", "error!~dead"}, []string{"order received", "card authorized"}},
+		{"field exists", []string{"-where", "error"}, []string{"query failed"}},
+		{"every expression", []string{"-where", "service=checkout", "-where", "level=info"}, []string{"order received"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			require.Equal(t, 0, run(append([]string{"-color", "never"}, tt.args...), strings.NewReader(logs), &out, &errOut), errOut.String())
+
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				for _, msg := range []string{"order received", "query failed", "card authorized", "starting"} {
+					if strings.Contains(line, msg) {
+						got = append(got, msg)
+					}
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRunGroup(t *testing.T) {
+	logs, spans, traceID := recordLogs(t)
+
+	var out, errOut bytes.Buffer
+	code := run([]string{"-color", "never", "-group", "-spans", spans}, strings.NewReader(logs), &out, &errOut)
+	require.Equal(t, 0, code, errOut.String())
+
+	groups := strings.Split(strings.TrimSpace(out.String()), "\n\n")
+	require.Len(t, groups, 2)
+
+	trace := strings.Split(groups[0], "\n")
+	require.Len(t, trace, 3)
+	assert.Regexp(t, `^── trace `+traceID+`  POST /orders \[checkout\]  \S+  2 lines$`, trace[0], "the header should show the root span")
+	assert.Contains(t, trace[1], `span="POST /orders"`)
+	assert.Contains(t, trace[2], `span="INSERT orders"`)
+	assert.NotContains(t, groups[0], "trace="+traceID, "the trace id is in the header")
+
+	other := strings.Split(groups[1], "\n")
+	assert.Equal(t, "── no trace  2 lines", other[0])
+	assert.Equal(t, "starting", other[1])
+}
+
+func TestParseExpr(t *testing.T) {
+	x, err := parseExpr("http.status_code>=500")
+	require.NoError(t, err)
+	assert.Equal(t, expr{key: "http.status_code", op: ">=", value: "500"}, x)
+
+	x, err = parseExpr("query=a>b")
+	require.NoError(t, err)
+	assert.Equal(t, expr{key: "query", op: "=", value: "a>b"}, x, "the first operator should split the expression")
+
+	for _, invalid := range []string{"", "=value", "path~(", " "} {
+		_, err := parseExpr(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	var out, errOut bytes.Buffer
+	assert.Equal(t, 2, run([]string{"-level", "loud"}, nil, &out, &errOut))
+	assert.Equal(t, 2, run([]string{"-where", "=1"}, nil, &out, &errOut))
+	assert.Equal(t, 2, run([]string{"-color", "sometimes"}, nil, &out, &errOut))
+	assert.Equal(t, 1, run([]string{"-spans", filepath.Join(t.TempDir(), "missing.jsonl")}, nil, &out, &errOut))
+	assert.Equal(t, 1, run([]string{filepath.Join(t.TempDir(), "missing.log")}, nil, &out, &errOut))
+}
@@ -0,0 +1,111 @@
+// Command traceview prints the traces in span files as indented waterfall trees, with the duration, status and
+// attributes of every span.
+//
+// It reads the output of the stdouttrace exporter, compact or pretty-printed, and OTLP/JSON lines such as those written
+// by tracing.FileExporter. Spans are grouped by trace id, so the spans of a trace can come from several files.
+//
+// Usage:
+//
+//	traceview [flags] [file ...]
+//
+// With no file, or the file `-`, the spans are read from stdin. The filters select the traces with at least one span
+// matching all of them, and the whole trace is printed:
+//
+//	-service name      a span of the service
+//	-name text         a span whose name contains text
+//	-min-duration d    a span that took at least d, such as 250ms
+//	-errors            a span that failed
+//
+// The other flags are -attrs=false to hide the attributes and -width to change the width of the waterfall bars.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/twistingmercury/telemetry/v2/internal/spanfile"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command and returns its exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("traceview", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: traceview [flags] [file ...]")
+		fs.PrintDefaults()
+	}
+
+	var f filter
+	fs.StringVar(&f.Service, "service", "", "only show traces with a span of this `service`")
+	fs.StringVar(&f.Name, "name", "", "only show traces with a span whose name contains this `text`")
+	fs.DurationVar(&f.MinDuration, "min-duration", 0, "only show traces with a span that took at least this `duration`")
+	fs.BoolVar(&f.ErrorsOnly, "errors", false, "only show traces with a span that failed")
+	attrs := fs.Bool("attrs", true, "print the attributes of the spans")
+	width := fs.Int("width", 40, "the width of the waterfall bars")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *width < 1 {
+		fmt.Fprintln(stderr, "traceview: the width must be at least 1")
+		return 2
+	}
+
+	spans, err := readFiles(fs.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "traceview: %s\n", err)
+		return 1
+	}
+
+	p := printer{w: stdout, barWidth: *width, attrs: *attrs}
+	printed := 0
+	for _, t := range buildTraces(spans) {
+		if f.selects(t) {
+			p.print(t)
+			printed++
+		}
+	}
+	if printed == 0 {
+		fmt.Fprintln(stderr, "traceview: no matching traces")
+	}
+	return 0
+}
+
+// readFiles reads the spans of every file, or of stdin when there is none.
+func readFiles(names []string, stdin io.Reader) ([]*spanfile.Span, error) {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+
+	var spans []*spanfile.Span
+	for _, name := range names {
+		read, err := readFile(name, stdin)
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, read...)
+	}
+	return spans, nil
+}
+
+func readFile(name string, stdin io.Reader) ([]*spanfile.Span, error) {
+	if name == "-" {
+		return spanfile.Read(stdin)
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	spans, err := spanfile.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read `%s`: %w", name, err)
+	}
+	return spans, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// recordTraces records two traces of the checkout service with exporter: a slow one with a failed database query,
+// and a fast one. It returns their trace ids.
+func recordTraces(t *testing.T, exporter sdktrace.SpanExporter) (slow, fast string) {
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "checkout"))),
+	)
+	tracer := tp.Tracer("traceview-test")
+
+	ctx, root := tracer.Start(context.Background(), "GET /checkout", oteltrace.WithSpanKind(oteltrace.SpanKindServer))
+	_, query := tracer.Start(ctx, "SELECT users", oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(attribute.String("db.system", "postgresql"), attribute.Int("rows", 3)))
+	time.Sleep(20 * time.Millisecond)
+	query.SetStatus(codes.Error, "timeout")
+	query.End()
+	_, render := tracer.Start(ctx, "render")
+	render.End()
+	root.End()
+
+	_, health := tracer.Start(context.Background(), "GET /health", oteltrace.WithSpanKind(oteltrace.SpanKindServer))
+	health.End()
+
+	require.NoError(t, tp.Shutdown(context.Background()))
+	return root.SpanContext().TraceID().String(), health.SpanContext().TraceID().String()
+}
+
+func TestRunStdouttrace(t *testing.T) {
+	var in bytes.Buffer
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(&in), stdouttrace.WithPrettyPrint())
+	require.NoError(t, err)
+	slow, _ := recordTraces(t, exporter)
+
+	var out, errOut bytes.Buffer
+	code := run([]string{"-width", "20"}, &in, &out, &errOut)
+	require.Equal(t, 0, code, errOut.String())
+
+	lines := strings.Split(out.String(), "\n")
+	assert.True(t, strings.HasPrefix(lines[0], "trace "+slow+"  3 spans  "), lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "GET /checkout (server) [checkout]"), lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "├─ SELECT users (client) "), lines[2])
+	assert.Contains(t, lines[2], "ERROR")
+	assert.Regexp(t, `\|[ =]{20}\|$`, lines[2], "every span should have a waterfall bar")
+	assert.Equal(t, "│       error: timeout", lines[3])
+	assert.Equal(t, "│       db.system=postgresql rows=3", lines[4])
+	assert.True(t, strings.HasPrefix(lines[5], "└─ render "), lines[5])
+	assert.Contains(t, out.String(), "GET /health", "the second trace should be printed")
+}
+
+func TestRunOTLPFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := tracing.NewFileExporter(tracing.FileExporterOptions{Path: path})
+	require.NoError(t, err)
+	slow, fast := recordTraces(t, exporter)
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"no filter", nil, []string{slow, fast}},
+		{"service", []string{"-service", "checkout"}, []string{slow, fast}},
+		{"other service", []string{"-service", "payments"}, nil},
+		{"name", []string{"-name", "health"}, []string{fast}},
+		{"min duration", []string{"-min-duration", "15ms"}, []string{slow}},
+		{"errors", []string{"-errors"}, []string{slow}},
+		{"all filters on one span", []string{"-errors", "-name", "render"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			require.Equal(t, 0, run(append(tt.args, path), nil, &out, &errOut))
+
+			var got []string
+			for _, line := range strings.Split(out.String(), "\n") {
+				if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "trace" {
+					got = append(got, fields[1])
+				}
+			}
+			assert.Equal(t, tt.want, got)
+			if tt.want == nil {
+				assert.Contains(t, errOut.String(), "no matching traces")
+			}
+		})
+	}
+}
+
+func TestRunOTLPNonFiniteDoubles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := tracing.NewFileExporter(tracing.FileExporterOptions{Path: path})
+	require.NoError(t, err)
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	_, span := tp.Tracer("traceview-test").Start(context.Background(), "score", oteltrace.WithAttributes(
+		attribute.Float64("ratio", 0.5),
+		attribute.Float64("score", math.NaN()),
+		attribute.Float64Slice("limits", []float64{math.Inf(-1), math.Inf(1)}),
+	))
+	span.End()
+	require.NoError(t, tp.Shutdown(context.Background()))
+
+	var out, errOut bytes.Buffer
+	require.Equal(t, 0, run([]string{path}, nil, &out, &errOut), errOut.String())
+	assert.Contains(t, out.String(), "ratio=0.5 score=NaN limits=[-Inf +Inf]")
+}
+
+func TestRunSkipsOtherLines(t *testing.T) {
+	var spans bytes.Buffer
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(&spans))
+	require.NoError(t, err)
+	slow, _ := recordTraces(t, exporter)
+
+	in := "starting\n" + `{"level":"info","message":"listening"}` + "\n" + spans.String() + "=== RUN TestSomething\n"
+
+	var out, errOut bytes.Buffer
+	require.Equal(t, 0, run([]string{"-attrs=false"}, strings.NewReader(in), &out, &errOut), errOut.String())
+	assert.Contains(t, out.String(), "trace "+slow)
+	assert.NotContains(t, out.String(), "db.system", "the attributes should be hidden")
+}
+
+func TestRunErrors(t *testing.T) {
+	var out, errOut bytes.Buffer
+	assert.Equal(t, 2, run([]string{"-unknown"}, nil, &out, &errOut))
+	assert.Equal(t, 2, run([]string{"-width", "0"}, nil, &out, &errOut))
+	assert.Equal(t, 1, run([]string{filepath.Join(t.TempDir(), "missing.jsonl")}, nil, &out, &errOut))
+}
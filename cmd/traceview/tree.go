@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/twistingmercury/telemetry/v2/internal/spanfile"
+)
+
+// trace is the spans sharing a trace id, linked to their parents.
+type trace struct {
+	ID       string
+	Start    time.Time
+	End      time.Time
+	spans    []*spanfile.Span
+	roots    []*spanfile.Span
+	children map[*spanfile.Span][]*spanfile.Span
+}
+
+// Duration returns the time from the start of the first span to the end of the last one.
+func (t *trace) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// buildTraces groups spans by trace id and links each span to its parent, ordered by start time. A span whose parent
+// is not in the input is a root, so partial traces are still shown. A span read more than once is only kept once.
+func buildTraces(spans []*spanfile.Span) []*trace {
+	byID := make(map[string]*trace)
+	var traces []*trace
+	seen := make(map[string]bool)
+	for _, s := range spans {
+		key := s.TraceID + "/" + s.SpanID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		t, ok := byID[s.TraceID]
+		if !ok {
+			t = &trace{ID: s.TraceID, Start: s.Start, End: s.End, children: make(map[*spanfile.Span][]*spanfile.Span)}
+			byID[s.TraceID] = t
+			traces = append(traces, t)
+		}
+		t.spans = append(t.spans, s)
+		if s.Start.Before(t.Start) {
+			t.Start = s.Start
+		}
+		if s.End.After(t.End) {
+			t.End = s.End
+		}
+	}
+
+	for _, t := range traces {
+		sort.SliceStable(t.spans, func(i, j int) bool { return t.spans[i].Start.Before(t.spans[j].Start) })
+		parents := make(map[string]*spanfile.Span, len(t.spans))
+		for _, s := range t.spans {
+			parents[s.SpanID] = s
+		}
+		for _, s := range t.spans {
+			if p, ok := parents[s.ParentID]; ok && s.ParentID != "" && p != s {
+				t.children[p] = append(t.children[p], s)
+			} else {
+				t.roots = append(t.roots, s)
+			}
+		}
+	}
+	sort.SliceStable(traces, func(i, j int) bool { return traces[i].Start.Before(traces[j].Start) })
+	return traces
+}
+
+// filter selects the traces that are printed. The zero value selects every trace.
+type filter struct {
+	Service     string
+	Name        string
+	MinDuration time.Duration
+	ErrorsOnly  bool
+}
+
+// matches reports whether s matches every condition of f.
+func (f filter) matches(s *spanfile.Span) bool {
+	return (f.Service == "" || s.Service == f.Service) &&
+		(f.Name == "" || strings.Contains(s.Name, f.Name)) &&
+		s.Duration() >= f.MinDuration &&
+		(!f.ErrorsOnly || s.Error)
+}
+
+// selects reports whether a span of t matches f, so the whole trace is printed around it.
+func (f filter) selects(t *trace) bool {
+	for _, s := range t.spans {
+		if f.matches(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// printer writes traces as indented trees, with a waterfall bar showing when each span ran within its trace.
+type printer struct {
+	w        io.Writer
+	barWidth int
+	attrs    bool
+}
+
+// row is a line of the tree.
+type row struct {
+	label  string
+	detail string
+	span   *spanfile.Span
+}
+
+func (p printer) print(t *trace) {
+	var rows []row
+	for _, s := range t.roots {
+		rows = p.appendRows(rows, t, s, "", "", "")
+	}
+
+	width := 0
+	for _, r := range rows {
+		width = max(width, utf8.RuneCountInString(r.label))
+	}
+
+	fmt.Fprintf(p.w, "trace %s  %d spans  %s\n", t.ID, len(t.spans), formatDuration(t.Duration()))
+	for _, r := range rows {
+		status := ""
+		if r.span.Error {
+			status = "ERROR"
+		}
+		fmt.Fprintf(p.w, "%-*s  %10s  %-5s  |%s|\n", width, r.label, formatDuration(r.span.Duration()), status, p.bar(t, r.span))
+		if r.span.Error && r.span.Status != "" {
+			fmt.Fprintf(p.w, "%s  error: %s\n", r.detail, r.span.Status)
+		}
+		if p.attrs && len(r.span.Attributes) > 0 {
+			attrs := make([]string, 0, len(r.span.Attributes))
+			for _, a := range r.span.Attributes {
+				attrs = append(attrs, a.Key+"="+a.Value)
+			}
+			fmt.Fprintf(p.w, "%s  %s\n", r.detail, strings.Join(attrs, " "))
+		}
+	}
+	fmt.Fprintln(p.w)
+}
+
+// appendRows appends the rows of s and its children. prefix is drawn before the span and indent before its
+// children; parentService is omitted from the label when s belongs to the same service.
+func (p printer) appendRows(rows []row, t *trace, s *spanfile.Span, prefix, indent, parentService string) []row {
+	label := prefix + s.Name
+	if s.Kind != "" && s.Kind != "internal" && s.Kind != "unspecified" {
+		label += " (" + s.Kind + ")"
+	}
+	if s.Service != "" && s.Service != parentService {
+		label += " [" + s.Service + "]"
+	}
+
+	detail := indent + "   "
+	children := t.children[s]
+	if len(children) > 0 {
+		detail = indent + "│  "
+	}
+	rows = append(rows, row{label: label, detail: detail, span: s})
+
+	for i, c := range children {
+		branch, next := "├─ ", "│  "
+		if i == len(children)-1 {
+			branch, next = "└─ ", "   "
+		}
+		rows = p.appendRows(rows, t, c, indent+branch, indent+next, s.Service)
+	}
+	return rows
+}
+
+// bar returns the waterfall bar of s: the columns covering the part of the trace during which it ran.
+func (p printer) bar(t *trace, s *spanfile.Span) string {
+	total := t.Duration()
+	if total <= 0 {
+		return strings.Repeat("=", p.barWidth)
+	}
+	from := int(float64(s.Start.Sub(t.Start)) / float64(total) * float64(p.barWidth))
+	length := int(math.Round(float64(s.Duration()) / float64(total) * float64(p.barWidth)))
+	from = min(max(from, 0), p.barWidth-1)
+	length = min(max(length, 1), p.barWidth-from)
+	return strings.Repeat(" ", from) + strings.Repeat("=", length) + strings.Repeat(" ", p.barWidth-from-length)
+}
+
+// formatDuration returns d rounded to three significant digits, such as 1.23s, 45.6ms or 789µs.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	case d >= 100*time.Millisecond:
+		return d.Round(time.Millisecond).String()
+	case d >= 10*time.Millisecond:
+		return d.Round(100 * time.Microsecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
@@ -0,0 +1,508 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+)
+
+// The OpenTelemetry environment variables read by LoadEnv. The OTLP variables also have a signal specific
+// OTEL_EXPORTER_OTLP_TRACES_* form, which takes precedence over the generic one.
+const (
+	envServiceName        = "OTEL_SERVICE_NAME"
+	envResourceAttributes = "OTEL_RESOURCE_ATTRIBUTES"
+	envTracesSampler      = "OTEL_TRACES_SAMPLER"
+	envTracesSamplerArg   = "OTEL_TRACES_SAMPLER_ARG"
+	envPropagators        = "OTEL_PROPAGATORS"
+	envTracesExporter     = "OTEL_TRACES_EXPORTER"
+	envOTLPPrefix         = "OTEL_EXPORTER_OTLP_"
+	envOTLPTracesPrefix   = "OTEL_EXPORTER_OTLP_TRACES_"
+)
+
+// envLogLevels is read by LoadEnv to set the levels of the logging components, such as `db=debug,http=warn`.
+const envLogLevels = "LOG_LEVELS"
+
+// redacted replaces the values of the OTLP headers in the [EnvValue] report, as they usually hold credentials.
+const redacted = "***"
+
+// EnvValue is an environment variable read by [LoadEnv].
+type EnvValue struct {
+	// Name is the name of the variable.
+	Name string
+	// Value is the value of the variable. The values of the OTLP headers are replaced with `***`.
+	Value string
+	// Applied is false when the variable was ignored, because the setting it configures was set explicitly or does
+	// not apply to the configured sampler or exporter.
+	Applied bool
+}
+
+// LoadEnv returns a copy of cfg with the settings that are not set explicitly read from the standard OpenTelemetry
+// environment variables:
+//
+//   - OTEL_SERVICE_NAME sets the service name.
+//   - OTEL_RESOURCE_ATTRIBUTES sets resource attributes, as a comma separated list of key=value pairs. The
+//     service.name, service.version and deployment.environment keys set the service name, version and environment.
+//   - OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG set the sampler type and its ratio, or spans per second for
+//     the rate limiting samplers.
+//   - OTEL_PROPAGATORS sets the propagators, as a comma separated list.
+//   - OTEL_TRACES_EXPORTER selects the trace exporter: otlp, console (stdout) or none, or one of the
+//     [tracing.ExporterType] values.
+//   - OTEL_EXPORTER_OTLP_ENDPOINT, _PROTOCOL, _HEADERS, _TIMEOUT (in milliseconds), _COMPRESSION, _INSECURE,
+//     _CERTIFICATE, _CLIENT_CERTIFICATE and _CLIENT_KEY, or their OTEL_EXPORTER_OTLP_TRACES_* forms, configure the
+//     OTLP trace exporter. The protocol, or an endpoint on its own, selects the OTLP exporter when no exporter type
+//     is set; the default protocol is http/protobuf.
+//   - LOG_LEVELS sets the levels of the logging components, as a comma separated list of component=level pairs,
+//     such as `db=debug,http=warn`. See [logging.Component].
+//
+// A setting is explicit when its field in cfg is not the zero value. Empty variables are ignored. Every variable
+// that is set is returned, with whether it was applied, so the caller can report the configuration. When a value is
+// not valid, an error naming every invalid variable is returned and cfg is returned unchanged.
+func LoadEnv(cfg Config) (Config, []EnvValue, error) {
+	l := &envLoader{cfg: cfg}
+	l.serviceName()
+	l.resourceAttributes()
+	l.sampler()
+	l.propagators()
+	l.exporter()
+	l.logLevels()
+
+	if err := errors.Join(l.errs...); err != nil {
+		return cfg, l.values, err
+	}
+	return l.cfg, l.values, nil
+}
+
+// SetupFromEnv is [Setup] with cfg completed by [LoadEnv]. Once logging is initialized, the environment variables
+// that were applied, and the ones that were overridden by explicit settings, are logged at the info level.
+func SetupFromEnv(ctx context.Context, cfg Config) (*Handle, []EnvValue, error) {
+	cfg, values, err := LoadEnv(cfg)
+	if err != nil {
+		return nil, values, err
+	}
+
+	h, err := Setup(ctx, cfg)
+	if err != nil {
+		return nil, values, err
+	}
+
+	if len(values) > 0 {
+		var args []logging.KeyValue
+		var overridden []string
+		for _, v := range values {
+			if v.Applied {
+				args = append(args, logging.KeyValue{Key: v.Name, Value: v.Value})
+			} else {
+				overridden = append(overridden, v.Name)
+			}
+		}
+		if len(overridden) > 0 {
+			args = append(args, logging.KeyValue{Key: "overridden", Value: overridden})
+		}
+		logging.Info(ctx, "configuration read from the environment", args...)
+	}
+	return h, values, nil
+}
+
+// envLoader applies the environment variables to cfg, recording the variables that were read and the errors.
+type envLoader struct {
+	cfg    Config
+	values []EnvValue
+	errs   []error
+}
+
+// lookup returns the value of the environment variable name. Empty values are treated as not set.
+func lookup(name string) (string, bool) {
+	v := strings.TrimSpace(os.Getenv(name))
+	return v, v != ""
+}
+
+// lookupOTLP returns the value of the OTLP trace exporter setting, preferring the signal specific variable.
+func lookupOTLP(setting string) (name, value string, ok bool) {
+	for _, name = range []string{envOTLPTracesPrefix + setting, envOTLPPrefix + setting} {
+		if value, ok = lookup(name); ok {
+			return name, value, true
+		}
+	}
+	return "", "", false
+}
+
+func (l *envLoader) record(name, value string, applied bool) {
+	l.values = append(l.values, EnvValue{Name: name, Value: value, Applied: applied})
+}
+
+func (l *envLoader) fail(name, value, reason string) {
+	l.errs = append(l.errs, fmt.Errorf("invalid %s: `%s`; %s", name, value, reason))
+}
+
+func (l *envLoader) serviceName() {
+	v, ok := lookup(envServiceName)
+	if !ok {
+		return
+	}
+	applied := l.cfg.ServiceName == ""
+	if applied {
+		l.cfg.ServiceName = v
+	}
+	l.record(envServiceName, v, applied)
+}
+
+func (l *envLoader) resourceAttributes() {
+	v, ok := lookup(envResourceAttributes)
+	if !ok {
+		return
+	}
+	pairs, err := parsePairs(v)
+	if err != nil {
+		l.fail(envResourceAttributes, v, err.Error())
+		return
+	}
+
+	explicit := make(map[attribute.Key]bool, len(l.cfg.ResourceAttributes))
+	for _, a := range l.cfg.ResourceAttributes {
+		explicit[a.Key] = true
+	}
+
+	applied := false
+	setIfEmpty := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+			applied = true
+		}
+	}
+	for _, p := range pairs {
+		switch attribute.Key(p.key) {
+		case semconv.ServiceNameKey:
+			setIfEmpty(&l.cfg.ServiceName, p.value)
+		case semconv.ServiceVersionKey:
+			setIfEmpty(&l.cfg.ServiceVersion, p.value)
+		case semconv.DeploymentEnvironmentKey:
+			setIfEmpty(&l.cfg.Environment, p.value)
+		default:
+			if !explicit[attribute.Key(p.key)] {
+				l.cfg.ResourceAttributes = append(l.cfg.ResourceAttributes, attribute.String(p.key, p.value))
+				applied = true
+			}
+		}
+	}
+	l.record(envResourceAttributes, v, applied)
+}
+
+func (l *envLoader) sampler() {
+	typ, hasType := lookup(envTracesSampler)
+	arg, hasArg := lookup(envTracesSamplerArg)
+
+	if l.cfg.Sampler.Type != "" || !hasType {
+		if hasType {
+			l.record(envTracesSampler, typ, false)
+		}
+		if hasArg {
+			l.record(envTracesSamplerArg, arg, false)
+		}
+		return
+	}
+
+	opts := l.cfg.Sampler
+	opts.Type = tracing.SamplerType(typ)
+	argApplied := false
+
+	switch opts.Type {
+	case tracing.SamplerTraceIDRatio, tracing.SamplerParentBasedTraceIDRatio:
+		// the ratio defaults to 1 when the argument is not set
+		opts.Ratio = 1
+		if hasArg {
+			r, err := strconv.ParseFloat(arg, 64)
+			if err != nil || r < 0 || r > 1 {
+				l.fail(envTracesSamplerArg, arg, "the ratio must be a number between 0 and 1")
+				return
+			}
+			opts.Ratio = r
+			argApplied = true
+		}
+		// a ratio of 0 samples no traces, which NewSampler only accepts as the always off samplers
+		if opts.Ratio == 0 {
+			opts.Type = tracing.SamplerAlwaysOff
+			if typ == string(tracing.SamplerParentBasedTraceIDRatio) {
+				opts.Type = tracing.SamplerParentBasedAlwaysOff
+			}
+		}
+	case tracing.SamplerRateLimiting, tracing.SamplerParentBasedRateLimiting:
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil || n <= 0 {
+			l.fail(envTracesSamplerArg, arg, "the rate limiting samplers require a number of spans per second greater than 0")
+			return
+		}
+		opts.SpansPerSecond = n
+		argApplied = true
+	}
+
+	if _, err := tracing.NewSampler(opts); err != nil {
+		l.fail(envTracesSampler, typ, err.Error())
+		return
+	}
+	l.cfg.Sampler = opts
+	l.record(envTracesSampler, typ, true)
+	if hasArg {
+		l.record(envTracesSamplerArg, arg, argApplied)
+	}
+}
+
+func (l *envLoader) logLevels() {
+	v, ok := lookup(envLogLevels)
+	if !ok {
+		return
+	}
+	levels, err := logging.ParseComponentLevels(v)
+	if err != nil {
+		l.fail(envLogLevels, v, err.Error())
+		return
+	}
+	applied := l.cfg.LogComponentLevels == nil
+	if applied {
+		l.cfg.LogComponentLevels = levels
+	}
+	l.record(envLogLevels, v, applied)
+}
+
+func (l *envLoader) propagators() {
+	v, ok := lookup(envPropagators)
+	if !ok {
+		return
+	}
+	if l.cfg.Propagators != nil {
+		l.record(envPropagators, v, false)
+		return
+	}
+
+	var formats []tracing.Propagator
+	for _, f := range strings.Split(v, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			formats = append(formats, tracing.Propagator(f))
+		}
+	}
+	if _, err := tracing.NewPropagator(formats...); err != nil {
+		l.fail(envPropagators, v, err.Error())
+		return
+	}
+	l.cfg.Propagators = formats
+	l.record(envPropagators, v, true)
+}
+
+// tracesExporters maps the values of OTEL_TRACES_EXPORTER to exporter types. otlp selects the OTLP exporter of
+// OTEL_EXPORTER_OTLP_PROTOCOL, and console is the standard name of the stdout exporter.
+var tracesExporters = map[string]tracing.ExporterType{
+	"otlp":                           "",
+	"console":                        tracing.ExporterStdout,
+	string(tracing.ExporterOTLPGRPC): tracing.ExporterOTLPGRPC,
+	string(tracing.ExporterOTLPHTTP): tracing.ExporterOTLPHTTP,
+	string(tracing.ExporterStdout):   tracing.ExporterStdout,
+	string(tracing.ExporterFile):     tracing.ExporterFile,
+	string(tracing.ExporterNone):     tracing.ExporterNone,
+}
+
+// otlpProtocols maps the values of OTEL_EXPORTER_OTLP_PROTOCOL to exporter types. http/json, although a standard
+// value, is not in the map: the OTLP exporters only send protobuf.
+var otlpProtocols = map[string]tracing.ExporterType{
+	"grpc":          tracing.ExporterOTLPGRPC,
+	"http/protobuf": tracing.ExporterOTLPHTTP,
+}
+
+// otlpProtocolHTTPJSON is the standard OTEL_EXPORTER_OTLP_PROTOCOL value that is not supported.
+const otlpProtocolHTTPJSON = "http/json"
+
+func isOTLP(t tracing.ExporterType) bool {
+	return t == tracing.ExporterOTLPGRPC || t == tracing.ExporterOTLPHTTP
+}
+
+func (l *envLoader) exporter() {
+	opts := &l.cfg.TraceExporterOptions
+
+	// the type is resolved first, as it decides whether the OTLP settings apply and the path of an endpoint set with
+	// the generic variable
+	otlpSelected := false
+	if v, ok := lookup(envTracesExporter); ok {
+		typ, valid := tracesExporters[v]
+		switch {
+		case !valid:
+			l.fail(envTracesExporter, v, "the exporter must be otlp, console, none, otlp-grpc, otlp-http, stdout or file")
+		case opts.Type != "":
+			l.record(envTracesExporter, v, false)
+		default:
+			opts.Type = typ
+			otlpSelected = typ == ""
+			l.record(envTracesExporter, v, true)
+		}
+	}
+
+	if name, v, ok := lookupOTLP("PROTOCOL"); ok {
+		typ, valid := otlpProtocols[v]
+		switch {
+		case v == otlpProtocolHTTPJSON:
+			l.fail(name, v, "the http/json protocol is not supported; use grpc or http/protobuf")
+		case !valid:
+			l.fail(name, v, "the protocol must be grpc or http/protobuf")
+		case opts.Type != "":
+			l.record(name, v, false)
+		default:
+			opts.Type = typ
+			l.record(name, v, true)
+		}
+	}
+
+	if name, v, ok := lookupOTLP("ENDPOINT"); ok {
+		u, err := url.Parse(v)
+		switch {
+		case err != nil || u.Scheme == "" || u.Host == "":
+			l.fail(name, v, "the endpoint must be a URL such as http://collector:4318")
+		case opts.Endpoint != "" || (opts.Type != "" && !isOTLP(opts.Type)):
+			l.record(name, v, false)
+		default:
+			// an endpoint on its own selects the default OTLP protocol
+			otlpSelected = true
+			if opts.Type == "" {
+				opts.Type = tracing.ExporterOTLPHTTP
+			}
+			// the generic endpoint is the base URL of every signal, so the HTTP exporter appends the traces path
+			if name == envOTLPPrefix+"ENDPOINT" && opts.Type == tracing.ExporterOTLPHTTP {
+				u.Path = strings.TrimSuffix(u.Path, "/") + tracing.OTLPTracesPath
+			}
+			opts.Endpoint = u.String()
+			l.record(name, v, true)
+		}
+	}
+
+	if otlpSelected && opts.Type == "" {
+		opts.Type = tracing.ExporterOTLPHTTP
+	}
+	// the remaining settings are only reported as applied when an OTLP exporter is used
+	otlp := isOTLP(opts.Type)
+
+	if name, v, ok := lookupOTLP("HEADERS"); ok {
+		pairs, err := parsePairs(v)
+		if err != nil {
+			l.fail(name, redactPairs(v), err.Error())
+		} else {
+			applied := otlp && opts.Headers == nil
+			if applied {
+				opts.Headers = make(map[string]string, len(pairs))
+				for _, p := range pairs {
+					opts.Headers[p.key] = p.value
+				}
+			}
+			l.record(name, redactPairs(v), applied)
+		}
+	}
+
+	if name, v, ok := lookupOTLP("TIMEOUT"); ok {
+		ms, err := strconv.Atoi(v)
+		switch {
+		case err != nil || ms < 0:
+			l.fail(name, v, "the timeout must be a number of milliseconds")
+		case !otlp || opts.Timeout != 0:
+			l.record(name, v, false)
+		default:
+			opts.Timeout = time.Duration(ms) * time.Millisecond
+			l.record(name, v, true)
+		}
+	}
+
+	if name, v, ok := lookupOTLP("COMPRESSION"); ok {
+		switch {
+		case v != tracing.CompressionGzip && v != "none":
+			l.fail(name, v, "the compression must be gzip or none")
+		case !otlp || opts.Compression != "":
+			l.record(name, v, false)
+		default:
+			if v == tracing.CompressionGzip {
+				opts.Compression = v
+			}
+			l.record(name, v, true)
+		}
+	}
+
+	if name, v, ok := lookupOTLP("INSECURE"); ok {
+		insecure, err := strconv.ParseBool(v)
+		switch {
+		case err != nil:
+			l.fail(name, v, "the value must be true or false")
+		case !otlp || opts.Insecure:
+			l.record(name, v, false)
+		default:
+			opts.Insecure = insecure
+			l.record(name, v, true)
+		}
+	}
+
+	files := []struct {
+		setting string
+		field   *string
+	}{
+		{"CERTIFICATE", &opts.CertificateFile},
+		{"CLIENT_CERTIFICATE", &opts.ClientCertificateFile},
+		{"CLIENT_KEY", &opts.ClientKeyFile},
+	}
+	for _, f := range files {
+		name, v, ok := lookupOTLP(f.setting)
+		if !ok {
+			continue
+		}
+		_, err := os.Stat(v)
+		switch {
+		case err != nil:
+			l.fail(name, v, "the file cannot be read")
+		case !otlp || opts.TLS != nil || *f.field != "":
+			l.record(name, v, false)
+		default:
+			*f.field = v
+			l.record(name, v, true)
+		}
+	}
+}
+
+// pair is a key=value pair of a comma separated list.
+type pair struct {
+	key   string
+	value string
+}
+
+// parsePairs parses a comma separated list of key=value pairs, such as OTEL_RESOURCE_ATTRIBUTES. The values are
+// percent-decoded.
+func parsePairs(s string) ([]pair, error) {
+	var pairs []pair
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(item, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, errors.New("the value must be a comma separated list of key=value pairs")
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("the value of `%s` is not correctly percent-encoded", k)
+		}
+		pairs = append(pairs, pair{key: k, value: decoded})
+	}
+	return pairs, nil
+}
+
+// redactPairs replaces the values of a comma separated list of key=value pairs with `***`.
+func redactPairs(s string) string {
+	var keys []string
+	for _, item := range strings.Split(s, ",") {
+		if k, _, _ := strings.Cut(item, "="); strings.TrimSpace(k) != "" {
+			keys = append(keys, strings.TrimSpace(k)+"="+redacted)
+		}
+	}
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,267 @@
+package telemetry_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestLoadEnv(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "env-service")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "service.version=2.0.0,deployment.environment=staging,k8s.pod.name=pod%201")
+	t.Setenv("OTEL_TRACES_SAMPLER", "parentbased_traceidratio")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.25")
+	t.Setenv("OTEL_PROPAGATORS", "tracecontext, b3multi")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=secret,tenant=a")
+	t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "500")
+	t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "gzip")
+
+	cfg, values, err := telemetry.LoadEnv(telemetry.Config{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "env-service", cfg.ServiceName)
+	assert.Equal(t, "2.0.0", cfg.ServiceVersion)
+	assert.Equal(t, "staging", cfg.Environment)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("k8s.pod.name", "pod 1")}, cfg.ResourceAttributes)
+	assert.Equal(t, tracing.SamplerParentBasedTraceIDRatio, cfg.Sampler.Type)
+	assert.Equal(t, 0.25, cfg.Sampler.Ratio)
+	assert.Equal(t, []tracing.Propagator{tracing.PropagatorTraceContext, tracing.PropagatorB3Multi}, cfg.Propagators)
+	assert.Equal(t, "http://collector:4318/v1/traces", cfg.TraceExporterOptions.Endpoint, "the traces path should be added to the generic endpoint")
+	assert.Equal(t, map[string]string{"api-key": "secret", "tenant": "a"}, cfg.TraceExporterOptions.Headers)
+	assert.Equal(t, 500*time.Millisecond, cfg.TraceExporterOptions.Timeout)
+	assert.Equal(t, tracing.CompressionGzip, cfg.TraceExporterOptions.Compression)
+	assert.Equal(t, tracing.ExporterOTLPHTTP, cfg.TraceExporterOptions.Type, "an endpoint should select the OTLP HTTP exporter")
+
+	require.Len(t, values, 9)
+	for _, v := range values {
+		assert.True(t, v.Applied, v.Name)
+		if v.Name == "OTEL_EXPORTER_OTLP_HEADERS" {
+			assert.Equal(t, "api-key=***,tenant=***", v.Value, "header values should be redacted")
+		}
+	}
+}
+
+func TestLoadEnvExplicitTakesPrecedence(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "env-service")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "service.name=resource-service,team=env")
+	t.Setenv("OTEL_TRACES_SAMPLER", "always_off")
+	t.Setenv("OTEL_PROPAGATORS", "xray")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://generic:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://traces:4318/custom")
+
+	cfg, values, err := telemetry.LoadEnv(telemetry.Config{
+		ServiceName:        "explicit-service",
+		ResourceAttributes: []attribute.KeyValue{attribute.String("team", "explicit")},
+		Sampler:            tracing.SamplerOptions{Type: tracing.SamplerAlwaysOn},
+		Propagators:        []tracing.Propagator{tracing.PropagatorB3},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "explicit-service", cfg.ServiceName)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("team", "explicit")}, cfg.ResourceAttributes)
+	assert.Equal(t, tracing.SamplerAlwaysOn, cfg.Sampler.Type)
+	assert.Equal(t, []tracing.Propagator{tracing.PropagatorB3}, cfg.Propagators)
+	assert.Equal(t, "http://traces:4318/custom", cfg.TraceExporterOptions.Endpoint, "the signal specific endpoint should be used as is")
+
+	applied := make(map[string]bool)
+	for _, v := range values {
+		applied[v.Name] = v.Applied
+	}
+	assert.Equal(t, map[string]bool{
+		"OTEL_SERVICE_NAME":                  false,
+		"OTEL_RESOURCE_ATTRIBUTES":           false,
+		"OTEL_TRACES_SAMPLER":                false,
+		"OTEL_PROPAGATORS":                   false,
+		"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": true,
+	}, applied)
+}
+
+func TestLoadEnvSamplerDefaults(t *testing.T) {
+	t.Setenv("OTEL_TRACES_SAMPLER", "traceidratio")
+
+	cfg, _, err := telemetry.LoadEnv(telemetry.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), cfg.Sampler.Ratio, "the ratio should default to 1 when no argument is set")
+
+	t.Setenv("OTEL_TRACES_SAMPLER", "parentbased_traceidratio")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0")
+	cfg, _, err = telemetry.LoadEnv(telemetry.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, tracing.SamplerParentBasedAlwaysOff, cfg.Sampler.Type, "a ratio of 0 should sample no traces")
+
+	t.Setenv("OTEL_TRACES_SAMPLER", "always_on")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.5")
+	_, values, err := telemetry.LoadEnv(telemetry.Config{})
+	require.NoError(t, err)
+	require.Len(t, values, 2)
+	assert.False(t, values[1].Applied, "the argument does not apply to always_on")
+}
+
+func TestLoadEnvLogLevels(t *testing.T) {
+	t.Setenv("LOG_LEVELS", "db=debug, http=WARN")
+
+	cfg, values, err := telemetry.LoadEnv(telemetry.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]zerolog.Level{"db": zerolog.DebugLevel, "http": zerolog.WarnLevel}, cfg.LogComponentLevels)
+	assert.Equal(t, []telemetry.EnvValue{{Name: "LOG_LEVELS", Value: "db=debug, http=WARN", Applied: true}}, values)
+
+	explicit := map[string]zerolog.Level{"db": zerolog.ErrorLevel}
+	cfg, values, err = telemetry.LoadEnv(telemetry.Config{LogComponentLevels: explicit})
+	require.NoError(t, err)
+	assert.Equal(t, explicit, cfg.LogComponentLevels)
+	assert.False(t, values[0].Applied)
+}
+
+func TestLoadEnvInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"OTEL_RESOURCE_ATTRIBUTES", "no-equals-sign"},
+		{"OTEL_TRACES_SAMPLER", "jaeger_remote"},
+		{"OTEL_PROPAGATORS", "ottrace"},
+		{"OTEL_EXPORTER_OTLP_ENDPOINT", "collector:4318"},
+		{"OTEL_EXPORTER_OTLP_PROTOCOL", "http/json"},
+		{"OTEL_EXPORTER_OTLP_TIMEOUT", "10s"},
+		{"OTEL_EXPORTER_OTLP_COMPRESSION", "zstd"},
+		{"OTEL_EXPORTER_OTLP_INSECURE", "maybe"},
+		{"OTEL_EXPORTER_OTLP_CERTIFICATE", "/does/not/exist.pem"},
+		{"OTEL_TRACES_EXPORTER", "zipkin"},
+		{"LOG_LEVELS", "db=loud"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			t.Setenv("OTEL_SERVICE_NAME", "env-service")
+
+			cfg, _, err := telemetry.LoadEnv(telemetry.Config{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.name)
+			assert.Empty(t, cfg.ServiceName, "the config should not be changed when a variable is invalid")
+		})
+	}
+
+	t.Run("http/json protocol", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "http/json")
+
+		_, _, err := telemetry.LoadEnv(telemetry.Config{})
+		assert.ErrorContains(t, err, "invalid OTEL_EXPORTER_OTLP_TRACES_PROTOCOL: `http/json`; the http/json protocol is not supported")
+	})
+
+	t.Run("sampler argument", func(t *testing.T) {
+		t.Setenv("OTEL_TRACES_SAMPLER", "traceidratio")
+		t.Setenv("OTEL_TRACES_SAMPLER_ARG", "1.5")
+		_, _, err := telemetry.LoadEnv(telemetry.Config{})
+		assert.ErrorContains(t, err, "OTEL_TRACES_SAMPLER_ARG")
+	})
+}
+
+func TestLoadEnvExporter(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		cfg  tracing.ExporterOptions
+		want tracing.ExporterOptions
+	}{
+		{
+			name: "otlp defaults to http",
+			env:  map[string]string{"OTEL_TRACES_EXPORTER": "otlp", "OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"},
+			want: tracing.ExporterOptions{Type: tracing.ExporterOTLPHTTP, Endpoint: "http://collector:4318/v1/traces"},
+		},
+		{
+			name: "otlp without endpoint",
+			env:  map[string]string{"OTEL_TRACES_EXPORTER": "otlp"},
+			want: tracing.ExporterOptions{Type: tracing.ExporterOTLPHTTP},
+		},
+		{
+			name: "grpc protocol",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "grpc", "OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4317"},
+			want: tracing.ExporterOptions{Type: tracing.ExporterOTLPGRPC, Endpoint: "http://collector:4317"},
+		},
+		{
+			name: "console",
+			env:  map[string]string{"OTEL_TRACES_EXPORTER": "console", "OTEL_EXPORTER_OTLP_TIMEOUT": "100"},
+			want: tracing.ExporterOptions{Type: tracing.ExporterStdout},
+		},
+		{
+			name: "explicit type",
+			env:  map[string]string{"OTEL_TRACES_EXPORTER": "otlp", "OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"},
+			cfg:  tracing.ExporterOptions{Type: tracing.ExporterNone},
+			want: tracing.ExporterOptions{Type: tracing.ExporterNone},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, _, err := telemetry.LoadEnv(telemetry.Config{TraceExporterOptions: tt.cfg})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cfg.TraceExporterOptions)
+		})
+	}
+}
+
+func TestSetupFromEnvOTLP(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		select {
+		case received <- r:
+		default:
+		}
+	}))
+	defer collector.Close()
+
+	t.Setenv("OTEL_SERVICE_NAME", "env-service")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=secret")
+
+	logs := &bytes.Buffer{}
+	h, values, err := telemetry.SetupFromEnv(context.Background(), telemetry.Config{LogWriter: logs})
+	require.NoError(t, err)
+	assert.Len(t, values, 3)
+
+	_, span := tracing.Start(context.Background(), "work", oteltrace.SpanKindInternal)
+	span.End()
+	require.NoError(t, h.Shutdown(context.Background()))
+
+	select {
+	case r := <-received:
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("api-key"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the spans were not exported to the OTLP endpoint")
+	}
+
+	assert.Contains(t, logs.String(), "configuration read from the environment")
+	assert.Contains(t, logs.String(), `"OTEL_SERVICE_NAME":"env-service"`)
+	assert.NotContains(t, logs.String(), "secret", "header values should not be logged")
+}
+
+func TestSetupFromEnvDefaultEndpoint(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "env-service")
+	t.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+
+	h, _, err := telemetry.SetupFromEnv(context.Background(), telemetry.Config{LogWriter: &bytes.Buffer{}})
+	require.NoError(t, err, "the OTLP endpoint should default to localhost")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_ = h.Shutdown(ctx)
+}
@@ -0,0 +1,304 @@
+// Package spanfile reads the spans written by the stdouttrace exporter and the OTLP/JSON lines written by
+// tracing.FileExporter, for the commands of this module.
+package spanfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// maxLineSize is the longest line read from a span file.
+const maxLineSize = 16 << 20
+
+// serviceNameKey is the resource attribute holding the name of the service.
+const serviceNameKey = "service.name"
+
+// Span is a span read from either format.
+type Span struct {
+	TraceID string
+	SpanID  string
+	// ParentID is the span id of the parent, or empty for a root span.
+	ParentID string
+	Name     string
+	// Service is the `service.name` resource attribute.
+	Service string
+	// Kind is the span kind, such as `server`.
+	Kind  string
+	Start time.Time
+	End   time.Time
+	// Error is true when the status of the span is error, and Status is its description.
+	Error      bool
+	Status     string
+	Attributes []Attribute
+}
+
+// Duration returns how long the span took.
+func (s *Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Attribute is an attribute of a span, with its value formatted as text.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Read reads the spans in r, which holds stdouttrace output, compact or pretty-printed, or OTLP/JSON lines.
+// Anything else, such as log lines written to the same stdout, is skipped.
+func Read(r io.Reader) ([]*Span, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var (
+		spans []*Span
+		buf   bytes.Buffer
+	)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		// A line starting with a brace starts a new value; pretty-printed values indent their other lines.
+		if len(line) > 0 && line[0] == '{' {
+			buf.Reset()
+		} else if buf.Len() == 0 {
+			continue
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		if !json.Valid(buf.Bytes()) {
+			continue
+		}
+
+		parsed, err := parseValue(buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, parsed...)
+		buf.Reset()
+	}
+	return spans, scanner.Err()
+}
+
+// parseValue returns the spans in a JSON value, or none when it is not a span.
+func parseValue(data []byte) ([]*Span, error) {
+	var probe struct {
+		ResourceSpans json.RawMessage `json:"resourceSpans"`
+		SpanContext   json.RawMessage `json:"SpanContext"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, nil
+	}
+	switch {
+	case probe.ResourceSpans != nil:
+		return parseOTLP(data)
+	case probe.SpanContext != nil:
+		s, err := parseStdout(data)
+		if err != nil {
+			return nil, err
+		}
+		return []*Span{s}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// stdoutSpan is a span written by go.opentelemetry.io/otel/exporters/stdout/stdouttrace.
+type stdoutSpan struct {
+	Name        string
+	SpanContext struct{ TraceID, SpanID string }
+	Parent      struct{ SpanID string }
+	SpanKind    int
+	StartTime   time.Time
+	EndTime     time.Time
+	Attributes  []stdoutKeyValue
+	Status      struct{ Code, Description string }
+	Resource    []stdoutKeyValue
+}
+
+type stdoutKeyValue struct {
+	Key   string
+	Value struct{ Value any }
+}
+
+func parseStdout(data []byte) (*Span, error) {
+	var in stdoutSpan
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&in); err != nil {
+		return nil, fmt.Errorf("invalid stdouttrace span: %w", err)
+	}
+
+	s := &Span{
+		TraceID: in.SpanContext.TraceID,
+		SpanID:  in.SpanContext.SpanID,
+		Name:    in.Name,
+		Kind:    kindName(in.SpanKind),
+		Start:   in.StartTime,
+		End:     in.EndTime,
+		Error:   in.Status.Code == "Error",
+		Status:  in.Status.Description,
+	}
+	if strings.Trim(in.Parent.SpanID, "0") != "" {
+		s.ParentID = in.Parent.SpanID
+	}
+	for _, kv := range in.Attributes {
+		s.Attributes = append(s.Attributes, Attribute{Key: kv.Key, Value: fmt.Sprint(kv.Value.Value)})
+	}
+	for _, kv := range in.Resource {
+		if kv.Key == serviceNameKey {
+			s.Service = fmt.Sprint(kv.Value.Value)
+		}
+	}
+	return s, nil
+}
+
+// otlpTracesData is an OTLP/JSON TracesData message, as written by tracing.FileExporter and the OpenTelemetry
+// Collector file exporter.
+type otlpTracesData struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []otlpKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Spans []otlpSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes"`
+	Status            struct {
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	} `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string      `json:"stringValue"`
+	BoolValue   *bool        `json:"boolValue"`
+	IntValue    *json.Number `json:"intValue"`
+	DoubleValue *otlpDouble  `json:"doubleValue"`
+	ArrayValue  *struct {
+		Values []otlpAnyValue `json:"values"`
+	} `json:"arrayValue"`
+}
+
+// otlpDouble is a double decoded as in the proto3 JSON mapping: a JSON number, or a string holding a number, "NaN",
+// "Infinity" or "-Infinity".
+type otlpDouble float64
+
+// UnmarshalJSON decodes a JSON number or string.
+func (d *otlpDouble) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid double: `%s`", data)
+	}
+	*d = otlpDouble(f)
+	return nil
+}
+
+// String returns the value as it is printed.
+func (v otlpAnyValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.IntValue != nil:
+		return v.IntValue.String()
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(float64(*v.DoubleValue), 'g', -1, 64)
+	case v.ArrayValue != nil:
+		values := make([]string, 0, len(v.ArrayValue.Values))
+		for _, e := range v.ArrayValue.Values {
+			values = append(values, e.String())
+		}
+		return "[" + strings.Join(values, " ") + "]"
+	default:
+		return ""
+	}
+}
+
+// otlpStatusError is the OTLP code of a span that failed.
+const otlpStatusError = 2
+
+func parseOTLP(data []byte) ([]*Span, error) {
+	var in otlpTracesData
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("invalid OTLP/JSON spans: %w", err)
+	}
+
+	var spans []*Span
+	for _, rs := range in.ResourceSpans {
+		var service string
+		for _, kv := range rs.Resource.Attributes {
+			if kv.Key == serviceNameKey {
+				service = kv.Value.String()
+			}
+		}
+		for _, ss := range rs.ScopeSpans {
+			for _, o := range ss.Spans {
+				start, err := parseUnixNano(o.StartTimeUnixNano)
+				if err != nil {
+					return nil, err
+				}
+				end, err := parseUnixNano(o.EndTimeUnixNano)
+				if err != nil {
+					return nil, err
+				}
+				s := &Span{
+					TraceID:  o.TraceID,
+					SpanID:   o.SpanID,
+					ParentID: o.ParentSpanID,
+					Name:     o.Name,
+					Service:  service,
+					Kind:     kindName(o.Kind),
+					Start:    start,
+					End:      end,
+					Error:    o.Status.Code == otlpStatusError,
+					Status:   o.Status.Message,
+				}
+				for _, kv := range o.Attributes {
+					s.Attributes = append(s.Attributes, Attribute{Key: kv.Key, Value: kv.Value.String()})
+				}
+				spans = append(spans, s)
+			}
+		}
+	}
+	return spans, nil
+}
+
+func parseUnixNano(v string) (time.Time, error) {
+	ns, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid span time: `%s`", v)
+	}
+	return time.Unix(0, ns), nil
+}
+
+// kindName returns the name of a span kind, which has the same value in both formats.
+func kindName(kind int) string {
+	return oteltrace.SpanKind(kind).String()
+}
@@ -0,0 +1,212 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// defaultQueueSize is the default of [AsyncWriterOptions.QueueSize].
+const defaultQueueSize = 1024
+
+// OverflowPolicy selects what an [AsyncWriter] does with a line written while its queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits for the queue to have room for the line. No line is dropped, but a slow writer stalls the
+	// callers again once the queue is full. It is the default.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest drops the oldest line of the queue to make room for the line.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDropNewest drops the line.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+)
+
+// The reasons a line is dropped, used as the `reason` label of the `log_dropped_lines_total` counter.
+const (
+	dropReasonOverflow = "overflow"
+	dropReasonClosed   = "closed"
+)
+
+// AsyncWriterOptions are the settings used by [NewAsyncWriter].
+type AsyncWriterOptions struct {
+	// QueueSize is the number of lines queued before Overflow applies. The default is 1024.
+	QueueSize int
+	// Overflow is what happens to a line written while the queue is full. The default is [OverflowBlock].
+	Overflow OverflowPolicy
+}
+
+// AsyncWriter is an [io.Writer] that queues the log lines and writes them to another writer from a goroutine, so a
+// slow stdout pipe or file does not stall the goroutines that log. When the queue is full, the lines are dropped or the
+// callers wait, as selected by [AsyncWriterOptions.Overflow]. The dropped lines are counted by the
+// `log_dropped_lines_total` counter when the metrics package is initialized.
+//
+// Call Flush to wait for the queued lines to be written, and Close to write them and stop the goroutine. A [Logger]
+// created with [Options.Async] does both: [Logger.Fatal], [Logger.Panic] and [Logger.ForceFlush] flush the writer,
+// and [Logger.Shutdown] closes it.
+type AsyncWriter struct {
+	w    io.Writer
+	opts AsyncWriterOptions
+
+	mu sync.Mutex
+	// cond is signalled whenever the queue, done, closed or stopped change.
+	cond  *sync.Cond
+	queue [][]byte
+	// queued is the number of lines added to the queue, and done the number of them written or dropped since.
+	queued, done uint64
+	closed       bool
+	// stopped is true once the goroutine has written the last line and returned.
+	stopped bool
+	dropped uint64
+}
+
+// NewAsyncWriter returns an [AsyncWriter] writing to w, and starts its goroutine. It returns an error when w is nil or
+// opts are not valid.
+func NewAsyncWriter(w io.Writer, opts AsyncWriterOptions) (*AsyncWriter, error) {
+	if w == nil {
+		return nil, errors.New("writer is required")
+	}
+	if opts.QueueSize < 0 {
+		return nil, fmt.Errorf("invalid queue size: `%d`", opts.QueueSize)
+	}
+	if opts.QueueSize == 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	switch opts.Overflow {
+	case "":
+		opts.Overflow = OverflowBlock
+	case OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+	default:
+		return nil, fmt.Errorf("invalid overflow policy: `%s`", opts.Overflow)
+	}
+
+	// Register the counter now, so it is exported before the first line is dropped.
+	droppedLines.get()
+
+	a := &AsyncWriter{w: w, opts: opts, queue: make([][]byte, 0, opts.QueueSize)}
+	a.cond = sync.NewCond(&a.mu)
+	go a.run()
+	return a, nil
+}
+
+// Write queues a copy of p. It never returns an error: a line dropped because the queue is full or a is closed is only
+// counted, so the logger does not report it on stderr for every message.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.opts.Overflow == OverflowBlock {
+		for len(a.queue) >= a.opts.QueueSize && !a.closed {
+			a.cond.Wait()
+		}
+	}
+	if a.closed {
+		a.drop(dropReasonClosed)
+		return len(p), nil
+	}
+
+	if len(a.queue) >= a.opts.QueueSize {
+		if a.opts.Overflow == OverflowDropNewest {
+			a.drop(dropReasonOverflow)
+			return len(p), nil
+		}
+		a.queue[0] = nil
+		a.queue = a.queue[1:]
+		a.done++
+		a.drop(dropReasonOverflow)
+	}
+
+	// The logger reuses the buffer of p once Write returns.
+	a.queue = append(a.queue, append([]byte(nil), p...))
+	a.queued++
+	a.cond.Broadcast()
+	return len(p), nil
+}
+
+// drop counts a dropped line. a.mu must be held.
+func (a *AsyncWriter) drop(reason string) {
+	a.dropped++
+	droppedLines.inc(reason)
+}
+
+// Dropped returns the number of lines dropped since a was created.
+func (a *AsyncWriter) Dropped() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dropped
+}
+
+// run writes the queued lines, one at a time, until a is closed and the queue is empty. The write errors are ignored,
+// as there is no caller left to report them to.
+func (a *AsyncWriter) run() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for {
+		for len(a.queue) == 0 && !a.closed {
+			a.cond.Wait()
+		}
+		if len(a.queue) == 0 {
+			a.stopped = true
+			a.cond.Broadcast()
+			return
+		}
+
+		line := a.queue[0]
+		a.queue[0] = nil
+		a.queue = a.queue[1:]
+		a.cond.Broadcast()
+		a.mu.Unlock()
+
+		_, _ = a.w.Write(line)
+
+		a.mu.Lock()
+		a.done++
+		a.cond.Broadcast()
+	}
+}
+
+// Flush waits until the lines queued before the call are written, or until ctx is done.
+func (a *AsyncWriter) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	target := a.queued
+	a.mu.Unlock()
+
+	return a.wait(ctx, func() bool { return a.done >= target })
+}
+
+// Close writes the queued lines and stops the goroutine of a, waiting until it is done or ctx is done. The lines
+// written after Close are dropped. The underlying writer is not closed. It is safe to call more than once.
+func (a *AsyncWriter) Close(ctx context.Context) error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		a.cond.Broadcast()
+	}
+	a.mu.Unlock()
+
+	return a.wait(ctx, func() bool { return a.stopped })
+}
+
+// wait waits until done, called with a.mu held, returns true, or until ctx is done.
+func (a *AsyncWriter) wait(ctx context.Context, done func() bool) error {
+	// Wake the loop below when ctx is done, as the condition may never become true.
+	stop := context.AfterFunc(ctx, func() {
+		a.mu.Lock()
+		a.cond.Broadcast()
+		a.mu.Unlock()
+	})
+	defer stop()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for !done() && !a.stopped {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		a.cond.Wait()
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package logging_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/telemetrytest"
+)
+
+// gatedWriter records the lines written to it. Each write signals started, and then waits for gate to be closed.
+type gatedWriter struct {
+	started chan struct{}
+	gate    chan struct{}
+
+	mu    sync.Mutex
+	lines []string
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{started: make(chan struct{}, 100), gate: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func (w *gatedWriter) Lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.lines...)
+}
+
+// slowWriter writes to a lockedBuffer after a delay.
+type slowWriter struct {
+	lockedBuffer
+	delay time.Duration
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.lockedBuffer.Write(p)
+}
+
+func newAsyncWriter(t *testing.T, w *gatedWriter, opts logging.AsyncWriterOptions) *logging.AsyncWriter {
+	t.Helper()
+	a, err := logging.NewAsyncWriter(w, opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = a.Close(context.Background()) })
+
+	// Write a line the goroutine is stuck writing until the gate is opened, so the queue fills up.
+	_, err = a.Write([]byte("a"))
+	require.NoError(t, err)
+	<-w.started
+	return a
+}
+
+func write(t *testing.T, w *logging.AsyncWriter, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		n, err := w.Write([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+}
+
+func TestAsyncWriter(t *testing.T) {
+	var buf slowWriter
+	buf.delay = time.Millisecond
+	a, err := logging.NewAsyncWriter(&buf, logging.AsyncWriterOptions{})
+	require.NoError(t, err)
+
+	line := []byte("1\n")
+	_, err = a.Write(line)
+	require.NoError(t, err)
+	line[0] = '9'
+	_, err = a.Write([]byte("2\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, a.Flush(context.Background()))
+	assert.Equal(t, "1\n2\n", buf.String(), "the lines should be copied and written in order")
+	assert.Zero(t, a.Dropped())
+	require.NoError(t, a.Close(context.Background()))
+}
+
+func TestAsyncWriterDropNewest(t *testing.T) {
+	tel := telemetrytest.New(t)
+	w := newGatedWriter()
+	a := newAsyncWriter(t, w, logging.AsyncWriterOptions{QueueSize: 2, Overflow: logging.OverflowDropNewest})
+
+	write(t, a, "b", "c", "d")
+	close(w.gate)
+	require.NoError(t, a.Flush(context.Background()))
+
+	assert.Equal(t, []string{"a", "b", "c"}, w.Lines())
+	assert.Equal(t, uint64(1), a.Dropped())
+	tel.AssertCounter("test_log_dropped_lines_total", map[string]string{"reason": "overflow"}, 1)
+}
+
+func TestAsyncWriterDropOldest(t *testing.T) {
+	w := newGatedWriter()
+	a := newAsyncWriter(t, w, logging.AsyncWriterOptions{QueueSize: 2, Overflow: logging.OverflowDropOldest})
+
+	write(t, a, "b", "c", "d", "e")
+	close(w.gate)
+	require.NoError(t, a.Flush(context.Background()))
+
+	assert.Equal(t, []string{"a", "d", "e"}, w.Lines())
+	assert.Equal(t, uint64(2), a.Dropped())
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	w := newGatedWriter()
+	a := newAsyncWriter(t, w, logging.AsyncWriterOptions{QueueSize: 2})
+
+	write(t, a, "b", "c")
+	written := make(chan struct{})
+	go func() {
+		_, _ = a.Write([]byte("d"))
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("the write should wait while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(w.gate)
+	<-written
+	require.NoError(t, a.Flush(context.Background()))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, w.Lines())
+	assert.Zero(t, a.Dropped())
+}
+
+func TestAsyncWriterFlushTimeout(t *testing.T) {
+	w := newGatedWriter()
+	a := newAsyncWriter(t, w, logging.AsyncWriterOptions{})
+	defer close(w.gate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, a.Flush(ctx), context.DeadlineExceeded)
+}
+
+func TestAsyncWriterFlushTimeoutReturns(t *testing.T) {
+	w := newGatedWriter()
+	a := newAsyncWriter(t, w, logging.AsyncWriterOptions{})
+	defer close(w.gate)
+
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 100; i++ {
+		assert.ErrorIs(t, a.Flush(ctx), context.Canceled)
+		assert.ErrorIs(t, a.Close(ctx), context.Canceled)
+	}
+	assert.Eventually(t, func() bool { return runtime.NumGoroutine() < before+10 }, time.Second, 10*time.Millisecond,
+		"a flush that timed out should not leave a goroutine waiting")
+}
+
+func TestAsyncWriterClose(t *testing.T) {
+	tel := telemetrytest.New(t)
+	w := newGatedWriter()
+	a := newAsyncWriter(t, w, logging.AsyncWriterOptions{})
+	write(t, a, "b")
+	close(w.gate)
+
+	require.NoError(t, a.Close(context.Background()))
+	assert.Equal(t, []string{"a", "b"}, w.Lines(), "the queued lines should be written before Close returns")
+
+	write(t, a, "c")
+	assert.Equal(t, []string{"a", "b"}, w.Lines(), "the lines written after Close should be dropped")
+	assert.Equal(t, uint64(1), a.Dropped())
+	tel.AssertCounter("test_log_dropped_lines_total", map[string]string{"reason": "closed"}, 1)
+
+	assert.NoError(t, a.Close(context.Background()))
+	assert.NoError(t, a.Flush(context.Background()))
+}
+
+func TestNewAsyncWriterErrors(t *testing.T) {
+	_, err := logging.NewAsyncWriter(nil, logging.AsyncWriterOptions{})
+	assert.EqualError(t, err, "writer is required")
+
+	_, err = logging.NewAsyncWriter(os.Stdout, logging.AsyncWriterOptions{QueueSize: -1})
+	assert.EqualError(t, err, "invalid queue size: `-1`")
+
+	_, err = logging.NewAsyncWriter(os.Stdout, logging.AsyncWriterOptions{Overflow: "drop-all"})
+	assert.EqualError(t, err, "invalid overflow policy: `drop-all`")
+
+	_, err = logging.New(logging.Options{Writer: os.Stdout, Async: &logging.AsyncWriterOptions{QueueSize: -1}})
+	assert.Error(t, err)
+}
+
+func TestLoggerAsync(t *testing.T) {
+	defer func() {
+		logging.SetExitFunc(os.Exit)
+	}()
+	logging.SetExitFunc(func(int) {})
+
+	ctx := context.Background()
+	buf := &slowWriter{delay: 10 * time.Millisecond}
+	l, err := logging.New(logging.Options{Level: zerolog.DebugLevel, Writer: buf, Async: &logging.AsyncWriterOptions{}})
+	require.NoError(t, err)
+
+	l.Info(ctx, "info")
+	l.With(logging.KeyValue{Key: "k", Value: "v"}).Fatal(ctx, errors.New("boom"), "fatal")
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"), "Fatal should write the queued lines before exiting")
+
+	l.Info(ctx, "info")
+	assert.Panics(t, func() { l.Component("db").Panic(ctx, errors.New("boom"), "panic") })
+	assert.Contains(t, buf.String(), `"message":"panic"`, "Panic should write the queued lines before panicking")
+
+	l.Info(ctx, "last")
+	require.NoError(t, l.Shutdown(ctx))
+	assert.Contains(t, buf.String(), `"message":"last"`, "Shutdown should write the queued lines")
+
+	l.Info(ctx, "after shutdown")
+	assert.NoError(t, l.Shutdown(ctx))
+	assert.NotContains(t, buf.String(), "after shutdown")
+}
@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// componentField is the field naming the component of the messages written by a [Logger.Component] logger.
+const componentField = "component"
+
+// componentLevels are the levels of the components of a [Logger], shared by all the loggers created from it. A
+// component without a level of its own writes at the level of the logger.
+type componentLevels struct {
+	root *levelVar
+
+	mu     sync.Mutex
+	levels map[string]*levelVar
+}
+
+func newComponentLevels(root *levelVar, levels map[string]zerolog.Level) *componentLevels {
+	c := &componentLevels{root: root, levels: make(map[string]*levelVar, len(levels))}
+	for name, level := range levels {
+		c.level(name).set(level)
+	}
+	return c
+}
+
+// level returns the level of the component, creating it without a level of its own if required.
+func (c *componentLevels) level(name string) *levelVar {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.levels[name]
+	if !ok {
+		v = newLevelVar(inheritLevel)
+		v.parent = c.root
+		c.levels[name] = v
+	}
+	return v
+}
+
+// names returns the sorted names of the components that have a level of their own, or a pending revert.
+func (c *componentLevels) names() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.levels))
+	for name, v := range c.levels {
+		if _, _, pending := v.pendingRevert(); pending || v.override() != inheritLevel {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Component returns a child [Logger] for the named part of the application, such as `db` or `http`, that adds the
+// `component` field to every message, replacing the component of l. Its level is the level of the component, set with [Options.ComponentLevels],
+// [Logger.SetComponentLevel] or the [LevelHandler], or the level of l when the component has no level of its own. The
+// loggers returned for the same name share the level of the component.
+func (l *Logger) Component(name string) *Logger {
+	if l.followDefault {
+		return &Logger{fields: l.fields, component: name, followDefault: true}
+	}
+	child := *l
+	child.level = l.components.level(name)
+	child.component = name
+	return &child
+}
+
+// addComponent adds the component of l to the fields of a message, unless they already have a component field.
+func (l *Logger) addComponent(fields map[string]any) {
+	if l.component == "" {
+		return
+	}
+	if _, ok := fields[componentField]; !ok {
+		fields[componentField] = l.component
+	}
+}
+
+// SetComponentLevel sets the level of the named component, overriding the level of l for its loggers. It cancels any
+// pending revert of a change made by the [LevelHandler].
+func (l *Logger) SetComponentLevel(name string, level zerolog.Level) {
+	l.resolve().components.level(name).set(level)
+}
+
+// ClearComponentLevel removes the level of the named component, so its loggers write at the level of l again.
+func (l *Logger) ClearComponentLevel(name string) {
+	l.resolve().components.level(name).set(inheritLevel)
+}
+
+// ComponentLevels returns the levels of the components that have a level of their own.
+func (l *Logger) ComponentLevels() map[string]zerolog.Level {
+	l = l.resolve()
+	levels := make(map[string]zerolog.Level)
+	for _, name := range l.components.names() {
+		if level := l.components.level(name).override(); level != inheritLevel {
+			levels[name] = level
+		}
+	}
+	return levels
+}
+
+// Component returns a [Logger] for the named part of the application that writes through the component of the default
+// [Logger], looked up for every message, so it can be a package variable created before [Initialize] or [SetDefault]:
+//
+//	var log = logging.Component("db")
+//
+// See [Logger.Component].
+func Component(name string) *Logger {
+	return &Logger{component: name, followDefault: true}
+}
+
+// resolve returns the logger l writes with: l itself, or for a logger returned by the package level [Component], the
+// component of the default logger with the fields added to l, redacted by the policy of the default logger.
+func (l *Logger) resolve() *Logger {
+	if !l.followDefault {
+		return l
+	}
+	c := Default().Component(l.component)
+	if len(l.fields) > 0 {
+		c.fields = MergeMaps(c.fields, c.policy.Fields(l.fields))
+	}
+	return c
+}
+
+// SetComponentLevel sets the level of the named component of the default [Logger].
+func SetComponentLevel(name string, level zerolog.Level) {
+	Default().SetComponentLevel(name, level)
+}
+
+// ParseComponentLevels parses a list of component levels such as `db=debug,http=warn`, as read from the `LOG_LEVELS`
+// environment variable, for [Options.ComponentLevels].
+func ParseComponentLevels(spec string) (map[string]zerolog.Level, error) {
+	levels := make(map[string]zerolog.Level)
+	for _, entry := range strings.Split(spec, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid component level: `%s`; it must be a name=level pair", entry)
+		}
+		level, err := zerolog.ParseLevel(strings.ToLower(value))
+		if err != nil || level == zerolog.NoLevel {
+			return nil, fmt.Errorf("invalid level of component %s: `%s`", name, value)
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
@@ -0,0 +1,162 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/redact"
+)
+
+func TestComponent(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := context.Background()
+	l, err := logging.New(logging.Options{
+		Level:           zerolog.InfoLevel,
+		Writer:          &buf,
+		ComponentLevels: map[string]zerolog.Level{"db": zerolog.DebugLevel, "http": zerolog.WarnLevel},
+	})
+	require.NoError(t, err)
+
+	db := l.Component("db")
+	api := l.Component("http").With(logging.KeyValue{Key: "route", Value: "/orders"})
+	cache := l.Component("cache")
+
+	db.Debug(ctx, "db debug")
+	api.Info(ctx, "http info")
+	api.Warn(ctx, "http warn")
+	cache.Debug(ctx, "cache debug")
+	cache.Info(ctx, "cache info")
+	l.Debug(ctx, "root debug")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 3)
+	assert.Equal(t, "db debug", lines[0]["message"])
+	assert.Equal(t, "db", lines[0]["component"])
+	assert.Equal(t, "http warn", lines[1]["message"])
+	assert.Equal(t, "/orders", lines[1]["route"])
+	assert.Equal(t, "cache info", lines[2]["message"], "a component without a level should use the level of the logger")
+
+	assert.Equal(t, zerolog.DebugLevel, db.Level())
+	assert.Equal(t, zerolog.InfoLevel, cache.Level())
+	assert.Equal(t, map[string]zerolog.Level{"db": zerolog.DebugLevel, "http": zerolog.WarnLevel}, l.ComponentLevels())
+}
+
+func TestComponentLevelChanges(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(logging.Options{Level: zerolog.InfoLevel, Writer: &buf})
+	require.NoError(t, err)
+	db := l.Component("db")
+
+	l.SetLevel(zerolog.WarnLevel)
+	assert.Equal(t, zerolog.WarnLevel, db.Level(), "the component should follow the level of the logger")
+
+	l.SetComponentLevel("db", zerolog.TraceLevel)
+	assert.Equal(t, zerolog.TraceLevel, db.Level())
+	assert.Equal(t, zerolog.TraceLevel, l.Component("db").Level(), "the loggers of a component should share its level")
+
+	db.SetLevel(zerolog.ErrorLevel)
+	assert.Equal(t, zerolog.ErrorLevel, l.Component("db").Level())
+	assert.Equal(t, zerolog.WarnLevel, l.Level(), "setting the level of a component should not change the logger")
+
+	l.ClearComponentLevel("db")
+	assert.Equal(t, zerolog.WarnLevel, db.Level())
+	assert.Empty(t, l.ComponentLevels())
+}
+
+func TestComponentNested(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(logging.Options{Level: zerolog.InfoLevel, Writer: &buf})
+	require.NoError(t, err)
+
+	l.Component("db").Component("cache").Info(context.Background(), "nested")
+	slog.New(l.Component("slog").SlogHandler()).Info("from slog")
+
+	first, _, _ := strings.Cut(buf.String(), "\n")
+	assert.Equal(t, 1, strings.Count(first, `"component"`), "the component should be written once")
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "cache", lines[0]["component"])
+	assert.Equal(t, "slog", lines[1]["component"])
+}
+
+// packageLogger is created when the package is initialized, before the default logger is configured.
+var packageLogger = logging.Component("db").With(logging.KeyValue{Key: "password", Value: "hunter2"})
+
+func TestComponentBeforeInitialize(t *testing.T) {
+	defer logging.SetDefault(logging.Default())
+
+	var buf bytes.Buffer
+	policy, err := redact.New(redact.Options{Keys: []string{"password"}})
+	require.NoError(t, err)
+	l, err := logging.New(logging.Options{Level: zerolog.WarnLevel, Writer: &buf, Redaction: policy})
+	require.NoError(t, err)
+	logging.SetDefault(l)
+
+	ctx := context.Background()
+	packageLogger.Info(ctx, "filtered")
+	logging.SetComponentLevel("db", zerolog.DebugLevel)
+	assert.Equal(t, zerolog.DebugLevel, packageLogger.Level())
+	packageLogger.Info(ctx, "written")
+	packageLogger.Component("cache").Info(ctx, "filtered")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1, "the component should write through the default logger set after it was created")
+	assert.Equal(t, "written", lines[0]["message"])
+	assert.Equal(t, "db", lines[0]["component"])
+	assert.Equal(t, redact.Mask, lines[0]["password"], "the fields should be redacted by the default logger")
+}
+
+func TestParseComponentLevels(t *testing.T) {
+	levels, err := logging.ParseComponentLevels(" db=debug, http=WARN ,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]zerolog.Level{"db": zerolog.DebugLevel, "http": zerolog.WarnLevel}, levels)
+
+	levels, err = logging.ParseComponentLevels("")
+	require.NoError(t, err)
+	assert.Empty(t, levels)
+
+	for _, invalid := range []string{"db", "=debug", "db=loud", "db="} {
+		_, err := logging.ParseComponentLevels(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLevelHandlerComponents(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(logging.Options{Level: zerolog.InfoLevel, Writer: &buf})
+	require.NoError(t, err)
+	h := logging.LevelHandler(logging.LevelHandlerOptions{Logger: l})
+
+	code, _ := serveLevel(t, h, http.MethodPut, "/loglevel?component=db&level=debug", "")
+	require.Equal(t, http.StatusOK, code)
+	code, state := serveLevel(t, h, http.MethodPut, "/loglevel", `{"component":"http","level":"warn","timeout":"1h"}`)
+	require.Equal(t, http.StatusOK, code)
+
+	assert.Equal(t, "info", state.Level)
+	assert.Equal(t, zerolog.DebugLevel, l.Component("db").Level())
+	assert.Equal(t, "debug", state.Components["db"].Level)
+	assert.Equal(t, "warn", state.Components["http"].Level)
+	assert.Equal(t, "default", state.Components["http"].RevertLevel, "the component should revert to the level of the logger")
+
+	code, state = serveLevel(t, h, http.MethodPut, "/loglevel?component=db&level=default", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, state.Components, "db")
+	assert.Equal(t, zerolog.InfoLevel, l.Component("db").Level())
+
+	code, _ = serveLevel(t, h, http.MethodPut, "/loglevel?level=default", "")
+	assert.Equal(t, http.StatusBadRequest, code, "the default level should require a component")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 3)
+	assert.Equal(t, "db", lines[0]["component"])
+	assert.Equal(t, "debug", lines[0]["new_level"])
+	assert.Equal(t, "info", lines[2]["new_level"])
+}
@@ -0,0 +1,45 @@
+package logging
+
+import "context"
+
+type fieldsKey struct{}
+
+// ContextWithFields returns a copy of ctx carrying fields. The fields are added to every message logged with the
+// returned context (or any context derived from it). Calling ContextWithFields on a context that already carries
+// fields adds to them; a field with the same key replaces the existing one.
+//
+// When the same key is used in more than one place, each field is written once, with the value of the last of: the
+// service, version and environment of the [Logger], the fields added with [Logger.With], the context fields, the
+// call-site args and the tracing data.
+func ContextWithFields(ctx context.Context, fields ...KeyValue) context.Context {
+	existing := FieldsFromContext(ctx)
+	merged := make([]KeyValue, 0, len(existing)+len(fields))
+	merged = append(merged, existing...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FieldsFromContext returns the fields added to ctx by [ContextWithFields].
+func FieldsFromContext(ctx context.Context) []KeyValue {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]KeyValue)
+	return fields
+}
+
+// eventFields returns the fields written with a message: the fields of l, overridden by the context fields,
+// overridden by the call-site args, overridden by the tracing data. The context fields and args are redacted by the
+// policy of l.
+func (l *Logger) eventFields(ctx context.Context, args []KeyValue) map[string]any {
+	fields := l.withFields(MergeMaps(toMap(FieldsFromContext(ctx)...), toMap(args...)))
+	return MergeMaps(fields, getTracingAttributes(ctx))
+}
+
+// withFields returns the fields of l overridden by fields, which are redacted by the policy of l, with the component
+// of l.
+func (l *Logger) withFields(fields map[string]any) map[string]any {
+	merged := MergeMaps(l.fields, l.policy.Fields(fields))
+	l.addComponent(merged)
+	return merged
+}
@@ -0,0 +1,109 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestFieldsFromContext(t *testing.T) {
+	assert.Empty(t, logging.FieldsFromContext(context.Background()))
+
+	parent := logging.ContextWithFields(context.Background(), logging.KeyValue{Key: "request_id", Value: "r1"})
+	child := logging.ContextWithFields(parent, logging.KeyValue{Key: "user_id", Value: "u1"})
+
+	assert.Equal(t, []logging.KeyValue{{Key: "request_id", Value: "r1"}}, logging.FieldsFromContext(parent))
+	assert.Equal(t, []logging.KeyValue{
+		{Key: "request_id", Value: "r1"},
+		{Key: "user_id", Value: "u1"},
+	}, logging.FieldsFromContext(child))
+}
+
+func TestLoggingWithContextFields(t *testing.T) {
+	defer func() {
+		logging.SetExitFunc(os.Exit)
+	}()
+	logging.SetExitFunc(func(int) {})
+
+	var buf bytes.Buffer
+	err := logging.Initialize(zerolog.DebugLevel, &buf, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+
+	ctx := logging.ContextWithFields(context.Background(),
+		logging.KeyValue{Key: "request_id", Value: "r1"},
+		logging.KeyValue{Key: "tenant", Value: "acme"})
+
+	logging.Debug(ctx, "Debug message")
+	logging.Info(ctx, "Info message")
+	logging.Warn(ctx, "Warn message")
+	logging.Error(ctx, errors.New("test error"), "Error message")
+	logging.Fatal(ctx, errors.New("test error"), "Fatal message")
+	assert.Panics(t, func() {
+		logging.Panic(ctx, errors.New("test panic"), "Panic message")
+	})
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 6)
+	for _, line := range lines {
+		assert.Equal(t, "r1", line["request_id"], "%s should carry the context fields", line["message"])
+		assert.Equal(t, "acme", line["tenant"], "%s should carry the context fields", line["message"])
+	}
+}
+
+func TestContextFieldsPrecedence(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(logging.Options{Level: zerolog.DebugLevel, Writer: &buf})
+	require.NoError(t, err)
+
+	traceID := trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10}
+	spanID := trace.SpanID{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18}
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	ctx := logging.ContextWithFields(context.Background(),
+		logging.KeyValue{Key: "user_id", Value: "from-parent"},
+		logging.KeyValue{Key: "request_id", Value: "from-context"},
+		logging.KeyValue{Key: logging.TraceIDAttr, Value: "from-context"})
+	ctx = logging.ContextWithFields(ctx, logging.KeyValue{Key: "user_id", Value: "from-child"})
+	ctx = trace.ContextWithSpanContext(ctx, spanCtx)
+
+	l.Info(ctx, "Info message",
+		logging.KeyValue{Key: "request_id", Value: "from-args"},
+		logging.KeyValue{Key: logging.SpanIDAttr, Value: "from-args"})
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "from-child", lines[0]["user_id"], "later context fields should override earlier ones")
+	assert.Equal(t, "from-args", lines[0]["request_id"], "call-site args should override context fields")
+	assert.Equal(t, traceID.String(), lines[0][logging.TraceIDAttr], "tracing data should override context fields")
+	assert.Equal(t, spanID.String(), lines[0][logging.SpanIDAttr], "tracing data should override call-site args")
+}
+
+func TestSlogHandlerWithContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(logging.Options{Level: zerolog.DebugLevel, Writer: &buf})
+	require.NoError(t, err)
+
+	ctx := logging.ContextWithFields(context.Background(),
+		logging.KeyValue{Key: "request_id", Value: "from-context"},
+		logging.KeyValue{Key: "tenant", Value: "acme"})
+
+	slog.New(l.SlogHandler()).InfoContext(ctx, "slog message", "request_id", "from-args")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "from-args", lines[0]["request_id"])
+	assert.Equal(t, "acme", lines[0]["tenant"])
+}
@@ -5,3 +5,12 @@ func SetExitFunc(f func(int)) {
 }
 
 var TraceInfo = traceInfo
+
+const MaxSampleKeys = maxSampleKeys
+
+// SampledKeys returns the number of distinct messages counted by the sampler of l in the current interval.
+func SampledKeys(l *Logger) int {
+	l.sampler.mu.Lock()
+	defer l.sampler.mu.Unlock()
+	return len(l.sampler.counts)
+}
@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// inheritLevel is the level of a component without an override, which writes at the level of its parent logger.
+const inheritLevel zerolog.Level = math.MinInt8
+
+// levelVar is the level of a [Logger] and of its child loggers, which can be changed while they are in use. A change
+// made with setFor is reverted to the level it replaced when its timeout expires, unless the level is changed again
+// first. The level of a component is inheritLevel until it is overridden, and then get returns the level of parent.
+type levelVar struct {
+	level  atomic.Int32
+	parent *levelVar
+
+	mu sync.Mutex
+	// revert is the pending revert of the last change made with setFor, or nil.
+	revert *time.Timer
+	// revertLevel is the level restored by revert.
+	revertLevel zerolog.Level
+	// revertAt is when revert runs.
+	revertAt time.Time
+}
+
+func newLevelVar(level zerolog.Level) *levelVar {
+	v := &levelVar{}
+	v.level.Store(int32(level))
+	return v
+}
+
+// get returns the current level, which is the level of the parent when v is not overridden.
+func (v *levelVar) get() zerolog.Level {
+	if level := v.override(); level != inheritLevel || v.parent == nil {
+		return level
+	}
+	return v.parent.get()
+}
+
+// override returns the level set on v, which is inheritLevel when v uses the level of its parent.
+func (v *levelVar) override() zerolog.Level {
+	return zerolog.Level(v.level.Load())
+}
+
+// set changes the level and cancels any pending revert.
+func (v *levelVar) set(level zerolog.Level) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.stopRevert()
+	v.level.Store(int32(level))
+}
+
+// setFor changes the level for d, and then calls reverted. When a revert is already pending, the level it restores is
+// kept, so consecutive temporary changes return to the level set before the first of them.
+func (v *levelVar) setFor(level zerolog.Level, d time.Duration, reverted func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	previous := zerolog.Level(v.level.Swap(int32(level)))
+	if v.revert == nil {
+		v.revertLevel = previous
+	}
+	v.stopRevert()
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		v.mu.Lock()
+		if v.revert != timer {
+			v.mu.Unlock()
+			return
+		}
+		v.revert = nil
+		v.level.Store(int32(v.revertLevel))
+		v.mu.Unlock()
+
+		if reverted != nil {
+			reverted()
+		}
+	})
+	v.revert = timer
+	v.revertAt = time.Now().Add(d)
+}
+
+// pendingRevert returns the level restored by the pending revert, and when, if one is pending.
+func (v *levelVar) pendingRevert() (zerolog.Level, time.Time, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.revertLevel, v.revertAt, v.revert != nil
+}
+
+// stopRevert cancels the pending revert. v.mu must be held.
+func (v *levelVar) stopRevert() {
+	if v.revert != nil {
+		v.revert.Stop()
+		v.revert = nil
+	}
+}
+
+// Level returns the minimum level written by l. It is shared with the loggers created from l with [Logger.With]. The
+// level of a logger returned by [Logger.Component] is the level of the component, or of its parent when the component
+// has no level of its own.
+func (l *Logger) Level() zerolog.Level {
+	return l.resolve().level.get()
+}
+
+// SetLevel changes the minimum level written by l and by the loggers created from it with [Logger.With], while they
+// are in use. On a logger returned by [Logger.Component], it sets the level of the component. It cancels any pending
+// revert of a change made by the [LevelHandler].
+func (l *Logger) SetLevel(level zerolog.Level) {
+	l.resolve().level.set(level)
+}
+
+// SetLevel changes the minimum level written by the default [Logger].
+func SetLevel(level zerolog.Level) {
+	Default().SetLevel(level)
+}
+
+// GetLevel returns the minimum level written by the default [Logger].
+func GetLevel() zerolog.Level {
+	return Default().Level()
+}
+
+// log returns the zerolog logger messages are written to, at the current level.
+func (l *Logger) log() zerolog.Logger {
+	return l.zl.Level(l.level.get())
+}
@@ -0,0 +1,209 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LevelHandlerOptions are the settings used by [LevelHandler].
+type LevelHandlerOptions struct {
+	// Logger is the logger whose level is read and changed. The default is the default logger, looked up on every
+	// request, so the handler follows any later call to [Initialize] or [SetDefault].
+	Logger *Logger
+	// DefaultTimeout is how long a level change lasts when the request does not set a timeout, after which the
+	// previous level is restored. Zero keeps the level until it is changed again.
+	DefaultTimeout time.Duration
+}
+
+// levelRequest is the body of a request changing the level, also accepted as query or form parameters.
+type levelRequest struct {
+	Level     string `json:"level"`
+	Timeout   string `json:"timeout"`
+	Component string `json:"component"`
+}
+
+// levelState is the level of a logger or component, and its pending revert.
+type levelState struct {
+	Level string `json:"level"`
+	// RevertLevel and RevertAt are set while a change with a timeout is pending.
+	RevertLevel string `json:"revert_level,omitempty"`
+	RevertAt    string `json:"revert_at,omitempty"`
+}
+
+// levelResponse is the body of every successful response of the [LevelHandler].
+type levelResponse struct {
+	levelState
+	// Components are the components that have a level of their own, or a pending revert.
+	Components map[string]levelState `json:"components,omitempty"`
+}
+
+// defaultLevelName is the level that removes the level of a component, so it uses the level of its logger again.
+const defaultLevelName = "default"
+
+// LevelHandler returns an [http.Handler] that reads and changes the minimum level of a [Logger], and of its
+// components, while the service is running, such as to turn on debug logging without a redeploy. Mount it on the
+// metrics server with [metrics.Handle], or on any mux:
+//
+//	GET  /loglevel                                {"level":"info","components":{"db":{"level":"debug"}}}
+//	PUT  /loglevel?level=debug&timeout=10m        {"level":"debug","revert_level":"info","revert_at":"..."}
+//	PUT  /loglevel  {"level":"warn"}              {"level":"warn"}
+//	PUT  /loglevel?component=db&level=default     {"level":"warn"}
+//
+// PUT and POST requests take the level, an optional component, and an optional timeout such as `10m` after which the
+// previous level is restored, as query or form parameters or as a JSON body. The level `default` removes the level of
+// a component, as set by [Logger.SetComponentLevel]. Each change, and each revert, is logged whatever the level, and
+// counted by the `log_level_changes_total` counter when the metrics package is initialized.
+//
+// The handler does not authenticate requests; mount it on a port that is not exposed publicly.
+func LevelHandler(opts LevelHandlerOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := opts.Logger
+		if l == nil {
+			l = Default()
+		}
+		l = l.resolve()
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut, http.MethodPost:
+			req, err := readLevelRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			level, timeout, err := parseLevelRequest(req, opts.DefaultTimeout)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			target := l
+			if req.Component != "" {
+				target = l.Component(req.Component)
+			}
+			target.changeLevel(r.Context(), level, timeout, r.RemoteAddr)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(l.levelResponse())
+	})
+}
+
+// readLevelRequest reads the parameters of a request changing the level from its JSON body, or from its query or form
+// parameters.
+func readLevelRequest(r *http.Request) (levelRequest, error) {
+	var req levelRequest
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<10)).Decode(&req); err != nil {
+			return req, fmt.Errorf("invalid request body: %w", err)
+		}
+		return req, nil
+	}
+
+	req.Level = r.FormValue("level")
+	req.Timeout = r.FormValue("timeout")
+	req.Component = r.FormValue("component")
+	return req, nil
+}
+
+// parseLevelRequest returns the level and timeout of req. The timeout is defaultTimeout when req does not set one.
+// The level is inheritLevel when req removes the level of a component.
+func parseLevelRequest(req levelRequest, defaultTimeout time.Duration) (zerolog.Level, time.Duration, error) {
+	var level zerolog.Level
+	switch name := strings.ToLower(req.Level); {
+	case name == "":
+		return zerolog.NoLevel, 0, errors.New("level is required")
+	case name == defaultLevelName:
+		if req.Component == "" {
+			return zerolog.NoLevel, 0, fmt.Errorf("the level `%s` requires a component", req.Level)
+		}
+		level = inheritLevel
+	default:
+		var err error
+		if level, err = zerolog.ParseLevel(name); err != nil || level == zerolog.NoLevel {
+			return zerolog.NoLevel, 0, fmt.Errorf("invalid level: `%s`", req.Level)
+		}
+	}
+
+	timeout := defaultTimeout
+	if req.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(req.Timeout); err != nil || timeout < 0 {
+			return zerolog.NoLevel, 0, fmt.Errorf("invalid timeout: `%s`", req.Timeout)
+		}
+	}
+	return level, timeout, nil
+}
+
+// changeLevel changes the level of l, for timeout when it is not zero, and logs and counts the change and its revert.
+// On a component logger, the messages have the component field, and the counter its label.
+func (l *Logger) changeLevel(ctx context.Context, level zerolog.Level, timeout time.Duration, remoteAddr string) {
+	previous := l.Level()
+	if timeout > 0 {
+		l.level.setFor(level, timeout, func() {
+			restored := l.Level()
+			levelChanges.inc(l.component, restored.String())
+			l.logLevelChange(context.Background(), "log level reverted",
+				KeyValue{Key: "new_level", Value: restored.String()})
+		})
+	} else {
+		l.level.set(level)
+	}
+
+	current := l.Level()
+	levelChanges.inc(l.component, current.String())
+	fields := []KeyValue{
+		{Key: "new_level", Value: current.String()},
+		{Key: "previous_level", Value: previous.String()},
+		{Key: "remote_addr", Value: remoteAddr},
+	}
+	if timeout > 0 {
+		fields = append(fields, KeyValue{Key: "timeout", Value: timeout.String()})
+	}
+	l.logLevelChange(ctx, "log level changed", fields...)
+}
+
+// logLevelChange logs a level change at the info level, even when the new level is higher, so every change is
+// recorded.
+func (l *Logger) logLevelChange(ctx context.Context, message string, args ...KeyValue) {
+	zl := l.zl.Level(zerolog.TraceLevel)
+	zl.Info().
+		Fields(l.eventFields(ctx, args)).
+		Msg(message)
+}
+
+// levelResponse returns the current level of l and of its components, and their pending reverts.
+func (l *Logger) levelResponse() levelResponse {
+	resp := levelResponse{levelState: stateOf(l.level)}
+	for _, name := range l.components.names() {
+		if resp.Components == nil {
+			resp.Components = make(map[string]levelState)
+		}
+		resp.Components[name] = stateOf(l.components.level(name))
+	}
+	return resp
+}
+
+// stateOf returns the current level of v and its pending revert.
+func stateOf(v *levelVar) levelState {
+	state := levelState{Level: v.get().String()}
+	if level, at, ok := v.pendingRevert(); ok {
+		state.RevertLevel = defaultLevelName
+		if level != inheritLevel {
+			state.RevertLevel = level.String()
+		}
+		state.RevertAt = at.UTC().Format(time.RFC3339)
+	}
+	return state
+}
@@ -0,0 +1,166 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/telemetrytest"
+)
+
+// levelState is the body of the responses of the level handler.
+type levelState struct {
+	Level       string `json:"level"`
+	RevertLevel string `json:"revert_level"`
+	RevertAt    string `json:"revert_at"`
+
+	Components map[string]levelState `json:"components"`
+}
+
+func serveLevel(t *testing.T, h http.Handler, method, target, body string) (int, levelState) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var state levelState
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state), rec.Body.String())
+	}
+	return rec.Code, state
+}
+
+func TestLoggerSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(logging.Options{Level: zerolog.InfoLevel, Writer: &buf})
+	require.NoError(t, err)
+	child := l.With(logging.KeyValue{Key: "component", Value: "db"})
+
+	child.Debug(context.Background(), "hidden")
+	l.SetLevel(zerolog.DebugLevel)
+	child.Debug(context.Background(), "shown")
+
+	assert.Equal(t, zerolog.DebugLevel, child.Level(), "the level should be shared with the child loggers")
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "shown", lines[0]["message"])
+}
+
+func TestLevelHandler(t *testing.T) {
+	tel := telemetrytest.New(t)
+	logging.SetLevel(zerolog.InfoLevel)
+	h := logging.LevelHandler(logging.LevelHandlerOptions{})
+
+	code, state := serveLevel(t, h, http.MethodGet, "/loglevel", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "info", state.Level)
+
+	code, state = serveLevel(t, h, http.MethodPut, "/loglevel?level=ERROR", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "error", state.Level)
+	assert.Empty(t, state.RevertAt)
+	assert.Equal(t, zerolog.ErrorLevel, logging.GetLevel())
+
+	code, state = serveLevel(t, h, http.MethodPost, "/loglevel", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "debug", state.Level)
+
+	tel.AssertLogged(zerolog.InfoLevel, "log level changed",
+		logging.KeyValue{Key: "new_level", Value: "error"}, logging.KeyValue{Key: "previous_level", Value: "info"})
+	tel.AssertLogged(zerolog.InfoLevel, "log level changed",
+		logging.KeyValue{Key: "new_level", Value: "debug"}, logging.KeyValue{Key: "previous_level", Value: "error"})
+	tel.AssertCounter("test_log_level_changes_total", map[string]string{"level": "error"}, 1)
+	tel.AssertCounter("test_log_level_changes_total", map[string]string{"level": "debug"}, 1)
+}
+
+func TestLevelHandlerErrors(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(logging.Options{Level: zerolog.InfoLevel, Writer: &buf})
+	require.NoError(t, err)
+	h := logging.LevelHandler(logging.LevelHandlerOptions{Logger: l})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"missing level", http.MethodPut, "/loglevel", "", http.StatusBadRequest},
+		{"invalid level", http.MethodPut, "/loglevel?level=loud", "", http.StatusBadRequest},
+		{"invalid timeout", http.MethodPut, "/loglevel?level=debug&timeout=soon", "", http.StatusBadRequest},
+		{"negative timeout", http.MethodPut, "/loglevel?level=debug&timeout=-1m", "", http.StatusBadRequest},
+		{"invalid body", http.MethodPut, "/loglevel", `{"level":`, http.StatusBadRequest},
+		{"method", http.MethodDelete, "/loglevel", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := serveLevel(t, h, tt.method, tt.target, tt.body)
+			assert.Equal(t, tt.want, code)
+		})
+	}
+	assert.Equal(t, zerolog.InfoLevel, l.Level())
+	assert.Empty(t, buf.String(), "rejected requests should not change the level")
+}
+
+func TestLevelHandlerTimeout(t *testing.T) {
+	var buf lockedBuffer
+	l, err := logging.New(logging.Options{Level: zerolog.InfoLevel, Writer: &buf})
+	require.NoError(t, err)
+	h := logging.LevelHandler(logging.LevelHandlerOptions{Logger: l, DefaultTimeout: time.Hour})
+
+	code, state := serveLevel(t, h, http.MethodPut, "/loglevel?level=debug", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "info", state.RevertLevel, "the default timeout should apply")
+	assert.NotEmpty(t, state.RevertAt)
+
+	code, state = serveLevel(t, h, http.MethodPut, "/loglevel?level=trace&timeout=50ms", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "trace", state.Level)
+	assert.Equal(t, "info", state.RevertLevel, "the level set before the first temporary change should be restored")
+
+	assert.Eventually(t, func() bool { return l.Level() == zerolog.InfoLevel }, time.Second, 5*time.Millisecond)
+	_, state = serveLevel(t, h, http.MethodGet, "/loglevel", "")
+	assert.Empty(t, state.RevertLevel)
+	assert.Eventually(t, func() bool { return strings.Contains(buf.String(), "log level reverted") }, time.Second, 5*time.Millisecond)
+
+	code, _ = serveLevel(t, h, http.MethodPut, "/loglevel?level=warn&timeout=0", "")
+	require.Equal(t, http.StatusOK, code)
+	l.SetLevel(zerolog.ErrorLevel)
+	_, state = serveLevel(t, h, http.MethodGet, "/loglevel", "")
+	assert.Equal(t, "error", state.Level)
+	assert.Empty(t, state.RevertLevel, "a zero timeout should keep the level")
+}
+
+// lockedBuffer is a bytes.Buffer that a timer goroutine can write to while the test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
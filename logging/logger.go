@@ -0,0 +1,315 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+	"github.com/twistingmercury/telemetry/v2/redact"
+	"go.opentelemetry.io/otel/attribute"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// fatalFlushTimeout is how long [Logger.Fatal] and [Logger.Panic] wait for buffered lines and records to be written
+// and exported.
+const fatalFlushTimeout = 5 * time.Second
+
+var defaultLogger atomic.Pointer[Logger]
+
+// init sets the zerolog globals used by every Logger once, rather than in New, so creating a Logger does not race with
+// the loggers already in use.
+func init() {
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	level := newLevelVar(zerolog.Disabled)
+	defaultLogger.Store(&Logger{zl: zerolog.Nop(), level: level, components: newComponentLevels(level, nil)})
+}
+
+// Options are the settings used by [New] to create a [Logger].
+type Options struct {
+	// Level is the minimum level written by the logger. It can be changed later with [Logger.SetLevel] or the
+	// [LevelHandler].
+	Level zerolog.Level
+	// ComponentLevels are the levels of the loggers returned by [Logger.Component] for the named components, such as
+	// `db`, overriding Level. Use [ParseComponentLevels] to read them from a spec such as `db=debug,http=warn`.
+	ComponentLevels map[string]zerolog.Level
+	// Writer is where the JSON log lines are written. It is required unless Exporter is set.
+	Writer io.Writer
+	// Async, when set, writes the lines to Writer from a goroutine through an [AsyncWriter], so a slow Writer does not
+	// stall the goroutines that log.
+	Async *AsyncWriterOptions
+	// Exporter, when set, also sends every message through the OpenTelemetry Logs SDK to the exporter, such as an
+	// OTLP exporter created with otlploghttp.New or otlploggrpc.New. Leave Writer nil to only use the exporter.
+	Exporter sdklog.Exporter
+	// ServiceName is written to every log line as the `service` field.
+	ServiceName string
+	// ServiceVersion is written to every log line as the `version` field.
+	ServiceVersion string
+	// Environment is written to every log line as the `environment` field.
+	Environment string
+	// Sampling, when set, limits how many messages with the same level and text are written in each interval.
+	Sampling *SamplingOptions
+	// ResourceAttributes are additional attributes set on the resource of the records sent to Exporter, such as
+	// `k8s.pod.name`. They are not written to the log lines.
+	ResourceAttributes []attribute.KeyValue
+	// Redaction, when set, redacts the sensitive values of the fields written with every message, including the
+	// fields added with [Logger.With] and [ContextWithFields]. The message, the error and the tracing data are not
+	// redacted.
+	Redaction *redact.Policy
+}
+
+// Logger writes structured log messages. Tracing data (if present) is automatically retrieved from the
+// [context.Context] passed to each method. A Logger is safe for concurrent use.
+type Logger struct {
+	zl zerolog.Logger
+	// fields are written with every message: the service, version and environment, and the redacted fields added with
+	// [Logger.With].
+	fields   map[string]any
+	provider *sdklog.LoggerProvider
+	policy   *redact.Policy
+	level    *levelVar
+	// components are the levels of the components, shared by all the loggers created from the same [New] call.
+	components *componentLevels
+	// component is the name of the component of a logger returned by [Logger.Component].
+	component string
+	// sampler is shared by all the loggers created from the same [New] call, and is nil when sampling is disabled.
+	sampler *sampler
+	// async is the writer created for [Options.Async], shared like sampler, or nil.
+	async *AsyncWriter
+	// followDefault is true for the loggers returned by the package level [Component], which only hold their component
+	// and the fields added with [Logger.With], and write through the default logger at the time of each message.
+	followDefault bool
+}
+
+// New creates a [Logger] configured by opts. Loggers created by New are independent of each other and of the
+// default logger used by the package functions.
+func New(opts Options) (*Logger, error) {
+	if opts.Writer == nil && opts.Exporter == nil {
+		return nil, errors.New("writer is required")
+	}
+
+	var provider *sdklog.LoggerProvider
+	var async *AsyncWriter
+	writers := make([]io.Writer, 0, 2)
+	if opts.Writer != nil && opts.Async != nil {
+		var err error
+		if async, err = NewAsyncWriter(opts.Writer, *opts.Async); err != nil {
+			return nil, err
+		}
+		writers = append(writers, async)
+	} else if opts.Writer != nil {
+		writers = append(writers, opts.Writer)
+	}
+	if opts.Exporter != nil {
+		var err error
+		provider, err = newOTelProvider(opts)
+		if err != nil {
+			if async != nil {
+				_ = async.Close(context.Background())
+			}
+			return nil, err
+		}
+		writers = append(writers, &otelWriter{logger: provider.Logger(instrumentationName)})
+	}
+
+	var w io.Writer = zerolog.MultiLevelWriter(writers...)
+	if len(writers) == 1 {
+		w = writers[0]
+	}
+
+	zl := zerolog.New(w).Hook(timestampHook{})
+
+	level := newLevelVar(opts.Level)
+	l := &Logger{
+		zl: zl,
+		fields: map[string]any{
+			"service":     opts.ServiceName,
+			"version":     opts.ServiceVersion,
+			"environment": opts.Environment,
+		},
+		provider:   provider,
+		policy:     opts.Redaction,
+		level:      level,
+		components: newComponentLevels(level, opts.ComponentLevels),
+		async:      async,
+	}
+	if opts.Sampling != nil {
+		var err error
+		if l.sampler, err = newSampler(*opts.Sampling, l.writeSamplingSummary); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Default returns the [Logger] used by the package functions, as configured by [Initialize].
+func Default() *Logger {
+	return defaultLogger.Load()
+}
+
+// SetDefault makes l the [Logger] used by the package functions.
+func SetDefault(l *Logger) {
+	if l != nil {
+		defaultLogger.Store(l)
+	}
+}
+
+// With returns a child [Logger] that adds fields to every message it writes. The parent logger is not modified. A
+// field replaces a field of l with the same key, and is replaced by the context fields and args of a message, as
+// described by [ContextWithFields].
+func (l *Logger) With(fields ...KeyValue) *Logger {
+	if l.followDefault {
+		// The fields are redacted when the message is written, by the policy of the default logger.
+		return &Logger{fields: MergeMaps(l.fields, toMap(fields...)), component: l.component, followDefault: true}
+	}
+	return &Logger{
+		zl:         l.zl,
+		fields:     MergeMaps(l.fields, l.policy.Fields(toMap(fields...))),
+		provider:   l.provider,
+		policy:     l.policy,
+		level:      l.level,
+		components: l.components,
+		component:  l.component,
+		sampler:    l.sampler,
+		async:      l.async,
+	}
+}
+
+// ForceFlush writes the summary of the messages suppressed by [Options.Sampling], waits for the lines queued by
+// [Options.Async] to be written, and exports all records buffered for the [Options.Exporter]. It is a no-op when none
+// is configured.
+func (l *Logger) ForceFlush(ctx context.Context) error {
+	l = l.resolve()
+	if l.sampler != nil {
+		l.sampler.flush()
+	}
+	var err error
+	if l.async != nil {
+		err = l.async.Flush(ctx)
+	}
+	if l.provider != nil {
+		err = errors.Join(err, l.provider.ForceFlush(ctx))
+	}
+	return err
+}
+
+// Shutdown writes the summary of the messages suppressed by [Options.Sampling], writes the lines queued by
+// [Options.Async] and closes its [AsyncWriter], and flushes all buffered records and shuts down the
+// [Options.Exporter]. It is a no-op when none is configured, and is safe to call more than once. Child loggers created
+// with [Logger.With] share the writer and exporter of their parent, so it only needs to be called once.
+func (l *Logger) Shutdown(ctx context.Context) error {
+	l = l.resolve()
+	if l.sampler != nil {
+		l.sampler.close()
+	}
+	var err error
+	if l.async != nil {
+		err = l.async.Close(ctx)
+	}
+	if l.provider != nil {
+		err = errors.Join(err, l.provider.Shutdown(ctx))
+	}
+	return err
+}
+
+// Debug logs a debug message. Tracing data (if present) is automatically retrieved from the [context.Context].
+func (l *Logger) Debug(ctx context.Context, message string, args ...KeyValue) {
+	l = l.resolve()
+	if !l.sampled(zerolog.DebugLevel, message) {
+		return
+	}
+	fields := l.eventFields(ctx, args)
+
+	zl := l.log()
+	zl.Debug().
+		Fields(fields).
+		Msg(message)
+}
+
+// Info logs an info message. Tracing data (if present) is automatically retrieved from the [context.Context].
+func (l *Logger) Info(ctx context.Context, message string, args ...KeyValue) {
+	l = l.resolve()
+	if !l.sampled(zerolog.InfoLevel, message) {
+		return
+	}
+	fields := l.eventFields(ctx, args)
+
+	zl := l.log()
+	zl.Info().
+		Fields(fields).
+		Msg(message)
+}
+
+// Warn logs a warning message. Tracing data (if present) is automatically retrieved from the [context.Context].
+func (l *Logger) Warn(ctx context.Context, message string, args ...KeyValue) {
+	l = l.resolve()
+	if !l.sampled(zerolog.WarnLevel, message) {
+		return
+	}
+	fields := l.eventFields(ctx, args)
+
+	zl := l.log()
+	zl.Warn().
+		Fields(fields).
+		Msg(message)
+}
+
+// Error logs an error message. Tracing data (if present) is automatically retrieved from the [context.Context].
+func (l *Logger) Error(ctx context.Context, err error, message string, args ...KeyValue) {
+	l = l.resolve()
+	if !l.sampled(zerolog.ErrorLevel, message) {
+		return
+	}
+	fields := l.eventFields(ctx, args)
+
+	zl := l.log()
+	zl.Error().
+		Fields(fields).
+		Err(err).
+		Str("is-fatal", "false").
+		Msg(message)
+}
+
+// Fatal logs a fatal message and exits the process. Tracing data (if present) is automatically retrieved from the
+// [context.Context].
+func (l *Logger) Fatal(ctx context.Context, err error, message string, args ...KeyValue) {
+	l = l.resolve()
+	fields := l.eventFields(ctx, args)
+
+	zl := l.log()
+	zl.Error().
+		Fields(fields).
+		Err(err).
+		Str("is-fatal", "true").
+		Msg(message)
+
+	l.flushBeforeExit()
+	exitFunc(1)
+}
+
+// Panic logs a panic message and then panics. Tracing data (if present) is automatically retrieved from the
+// [context.Context].
+func (l *Logger) Panic(ctx context.Context, err error, message string, args ...KeyValue) {
+	l = l.resolve()
+	defer l.flushBeforeExit()
+	fields := l.eventFields(ctx, args)
+
+	zl := l.log()
+	zl.Panic().
+		Fields(fields).
+		Err(err).
+		Msg(message)
+}
+
+// flushBeforeExit flushes l, waiting at most fatalFlushTimeout, so the last messages are written before the process
+// exits or panics.
+func (l *Logger) flushBeforeExit() {
+	ctx, cancel := context.WithTimeout(context.Background(), fatalFlushTimeout)
+	defer cancel()
+	_ = l.ForceFlush(ctx)
+}
@@ -0,0 +1,198 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+)
+
+func TestNewWithNilWriter(t *testing.T) {
+	l, err := logging.New(logging.Options{Level: zerolog.DebugLevel})
+	assert.Error(t, err, "New should return an error when writer is nil")
+	assert.Nil(t, l)
+}
+
+func TestNewIndependentLoggers(t *testing.T) {
+	var debugBuf, warnBuf bytes.Buffer
+	ctx := context.Background()
+
+	debugLogger, err := logging.New(logging.Options{
+		Level:          zerolog.DebugLevel,
+		Writer:         &debugBuf,
+		ServiceName:    "debug-service",
+		ServiceVersion: serviceVersion,
+		Environment:    environment,
+	})
+	require.NoError(t, err)
+
+	warnLogger, err := logging.New(logging.Options{
+		Level:          zerolog.WarnLevel,
+		Writer:         &warnBuf,
+		ServiceName:    "warn-service",
+		ServiceVersion: serviceVersion,
+		Environment:    environment,
+	})
+	require.NoError(t, err)
+
+	debugLogger.Debug(ctx, "Debug message", logging.KeyValue{Key: "key1", Value: "value1"})
+	warnLogger.Debug(ctx, "Debug message")
+	warnLogger.Warn(ctx, "Warn message", logging.KeyValue{Key: "key2", Value: 123})
+
+	debugLines := decodeLines(t, &debugBuf)
+	require.Len(t, debugLines, 1)
+	assert.Equal(t, "debug-service", debugLines[0]["service"])
+	assert.Equal(t, "Debug message", debugLines[0]["message"])
+	assert.Equal(t, "value1", debugLines[0]["key1"])
+
+	warnLines := decodeLines(t, &warnBuf)
+	require.Len(t, warnLines, 1)
+	assert.Equal(t, "warn-service", warnLines[0]["service"])
+	assert.Equal(t, "Warn message", warnLines[0]["message"])
+	assert.Equal(t, float64(123), warnLines[0]["key2"])
+}
+
+func TestLoggerWith(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := context.Background()
+
+	parent, err := logging.New(logging.Options{Level: zerolog.DebugLevel, Writer: &buf, ServiceName: serviceName})
+	require.NoError(t, err)
+
+	child := parent.With(logging.KeyValue{Key: "component", Value: "db"})
+	grandchild := child.With(logging.KeyValue{Key: "tenant", Value: "acme"})
+
+	child.Info(ctx, "child message")
+	grandchild.Info(ctx, "grandchild message")
+	parent.Info(ctx, "parent message")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 3)
+
+	assert.Equal(t, "db", lines[0]["component"])
+	assert.NotContains(t, lines[0], "tenant")
+
+	assert.Equal(t, "db", lines[1]["component"])
+	assert.Equal(t, "acme", lines[1]["tenant"])
+	assert.Equal(t, serviceName, lines[1]["service"])
+
+	assert.NotContains(t, lines[2], "component", "child fields must not be added to the parent")
+}
+
+// topLevelKeys returns the keys of the JSON object line in order, including the repeated ones.
+func topLevelKeys(t *testing.T, line string) []string {
+	t.Helper()
+
+	dec := json.NewDecoder(strings.NewReader(line))
+	tok, err := dec.Token()
+	require.NoError(t, err)
+	require.Equal(t, json.Delim('{'), tok)
+
+	var keys []string
+	for dec.More() {
+		tok, err = dec.Token()
+		require.NoError(t, err)
+		keys = append(keys, tok.(string))
+		var value json.RawMessage
+		require.NoError(t, dec.Decode(&value))
+	}
+	return keys
+}
+
+func TestLoggerWithNoDuplicateKeys(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(logging.Options{Level: zerolog.DebugLevel, Writer: &buf, ServiceName: serviceName})
+	require.NoError(t, err)
+
+	ctx := logging.ContextWithFields(context.Background(), logging.KeyValue{Key: "region", Value: "ctx"})
+	l.With(logging.KeyValue{Key: "tenant", Value: "a"}, logging.KeyValue{Key: "region", Value: "with"}).
+		Info(ctx, "message", logging.KeyValue{Key: "tenant", Value: "b"}, logging.KeyValue{Key: "service", Value: "other"})
+
+	line := strings.TrimSpace(buf.String())
+	keys := topLevelKeys(t, line)
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		assert.False(t, seen[key], "the key %q is written more than once: %s", key, line)
+		seen[key] = true
+	}
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "b", lines[0]["tenant"], "the args should override the With fields")
+	assert.Equal(t, "ctx", lines[0]["region"], "the context fields should override the With fields")
+	assert.Equal(t, "other", lines[0]["service"], "the args should override the service")
+}
+
+func TestLoggerMethods(t *testing.T) {
+	defer func() {
+		logging.SetExitFunc(os.Exit)
+	}()
+	exitCode := -1
+	logging.SetExitFunc(func(code int) { exitCode = code })
+
+	var buf bytes.Buffer
+	ctx := context.Background()
+
+	l, err := logging.New(logging.Options{Level: zerolog.DebugLevel, Writer: &buf})
+	require.NoError(t, err)
+
+	l.Debug(ctx, "Debug message")
+	l.Info(ctx, "Info message")
+	l.Warn(ctx, "Warn message")
+	l.Error(ctx, errors.New("test error"), "Error message")
+	l.Fatal(ctx, errors.New("test error"), "Fatal message")
+	assert.Panics(t, func() {
+		l.Panic(ctx, errors.New("test panic"), "Panic message")
+	})
+	assert.Equal(t, 1, exitCode, "Fatal should call the exit func")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 6)
+	levels := make([]any, 0, len(lines))
+	for _, line := range lines {
+		levels = append(levels, line["level"])
+	}
+	assert.Equal(t, []any{"debug", "info", "warn", "error", "error", "panic"}, levels)
+	assert.Equal(t, "false", lines[3]["is-fatal"])
+	assert.Equal(t, "true", lines[4]["is-fatal"])
+	assert.Equal(t, "test error", lines[4]["error"])
+}
+
+func TestSetDefault(t *testing.T) {
+	defer logging.SetDefault(logging.Default())
+
+	var buf bytes.Buffer
+	l, err := logging.New(logging.Options{Level: zerolog.DebugLevel, Writer: &buf})
+	require.NoError(t, err)
+
+	logging.SetDefault(l.With(logging.KeyValue{Key: "component", Value: "default"}))
+	logging.Info(context.Background(), "Info message")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "default", lines[0]["component"])
+}
+
+func TestLoggerSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(logging.Options{Level: zerolog.InfoLevel, Writer: &buf})
+	require.NoError(t, err)
+
+	log := slog.New(l.With(logging.KeyValue{Key: "component", Value: "slog"}).SlogHandler())
+	log.Debug("Debug message")
+	log.Info("Info message")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "slog", lines[0]["component"])
+	assert.Equal(t, "Info message", lines[0]["message"])
+}
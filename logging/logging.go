@@ -2,15 +2,11 @@ package logging
 
 import (
 	"context"
-	"errors"
 	"go.opentelemetry.io/otel/trace"
 	"io"
 	"os"
 
-	"time"
-
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/pkgerrors"
 )
 
 const (
@@ -19,7 +15,6 @@ const (
 )
 
 var (
-	logger   zerolog.Logger
 	exitFunc = os.Exit
 )
 
@@ -36,37 +31,43 @@ func toMap(values ...KeyValue) map[string]any {
 	return m
 }
 
-// Initialize initializes the logging system.
-// It returns a logger that can be used to log messages, though it is not required.
+// Initialize initializes the default [Logger] used by the package functions.
+// Use [New] to create additional, independently configured loggers.
 func Initialize(level zerolog.Level, writer io.Writer, serviceName, serviceVersion, environment string) (err error) {
-
-	if writer == nil {
-		return errors.New("writer is required")
+	l, err := New(Options{
+		Level:          level,
+		Writer:         writer,
+		ServiceName:    serviceName,
+		ServiceVersion: serviceVersion,
+		Environment:    environment,
+	})
+	if err != nil {
+		return
 	}
 
-	zerolog.SetGlobalLevel(level)
-	zerolog.TimeFieldFormat = time.RFC3339Nano
-	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
-
-	logger = zerolog.New(writer).
-		With().
-		Timestamp().
-		Str("service", serviceName).
-		Str("version", serviceVersion).
-		Str("environment", environment).
-		Logger()
-
+	SetDefault(l)
 	return
 }
 
+// Shutdown flushes and shuts down the async writer and the OpenTelemetry exporter of the default [Logger], if they are
+// configured. See [Logger.Shutdown].
+func Shutdown(ctx context.Context) error {
+	return Default().Shutdown(ctx)
+}
+
 // DebugWithContext logs a debug message and adds the trace id and span id found in the ctx.
 // The args are key value pairs and are optional.
 //
 // Deprecated: use Debug func instead. It extracts tracing data from the context automatically.
 func DebugWithContext(spanCtx *trace.SpanContext, message string, args ...KeyValue) {
 	tInf := traceInfo(spanCtx)
-	margs := MergeMaps(toMap(args...), tInf)
-	logger.Debug().
+	l := Default()
+	if !l.sampled(zerolog.DebugLevel, message) {
+		return
+	}
+	margs := MergeMaps(l.withFields(toMap(args...)), tInf)
+	zl := l.log()
+	zl.Debug().
 		Fields(margs).
 		Msg(message)
 }
@@ -77,8 +78,13 @@ func DebugWithContext(spanCtx *trace.SpanContext, message string, args ...KeyVal
 // Deprecated: use Info func instead. It extracts tracing data from the context automatically.
 func InfoWithContext(spanCtx *trace.SpanContext, message string, args ...KeyValue) {
 	tInf := traceInfo(spanCtx)
-	margs := MergeMaps(toMap(args...), tInf)
-	logger.Info().
+	l := Default()
+	if !l.sampled(zerolog.InfoLevel, message) {
+		return
+	}
+	margs := MergeMaps(l.withFields(toMap(args...)), tInf)
+	zl := l.log()
+	zl.Info().
 		Fields(margs).
 		Msg(message)
 }
@@ -89,8 +95,13 @@ func InfoWithContext(spanCtx *trace.SpanContext, message string, args ...KeyValu
 // Deprecated: use Warn func instead. It extracts tracing data from the context automatically.
 func WarnWithContext(spanCtx *trace.SpanContext, message string, args ...KeyValue) {
 	tInf := traceInfo(spanCtx)
-	margs := MergeMaps(toMap(args...), tInf)
-	logger.Warn().
+	l := Default()
+	if !l.sampled(zerolog.WarnLevel, message) {
+		return
+	}
+	margs := MergeMaps(l.withFields(toMap(args...)), tInf)
+	zl := l.log()
+	zl.Warn().
 		Fields(margs).
 		Msg(message)
 }
@@ -100,8 +111,13 @@ func WarnWithContext(spanCtx *trace.SpanContext, message string, args ...KeyValu
 // Deprecated: use Error func instead. It extracts tracing data from the context automatically.
 func ErrorWithContext(spanCtx *trace.SpanContext, err error, message string, args ...KeyValue) {
 	tInf := traceInfo(spanCtx)
-	margs := MergeMaps(toMap(args...), tInf)
-	logger.Error().
+	l := Default()
+	if !l.sampled(zerolog.ErrorLevel, message) {
+		return
+	}
+	margs := MergeMaps(l.withFields(toMap(args...)), tInf)
+	zl := l.log()
+	zl.Error().
 		Fields(margs).
 		Err(err).
 		Str("is-fatal", "false").
@@ -113,19 +129,25 @@ func ErrorWithContext(spanCtx *trace.SpanContext, err error, message string, arg
 // Deprecated: use Fatal func instead. It extracts tracing data from the context automatically.
 func FatalWithContext(spanCtx *trace.SpanContext, err error, message string, args ...KeyValue) {
 	tInf := traceInfo(spanCtx)
-	margs := MergeMaps(toMap(args...), tInf)
-	logger.Error().
+	l := Default()
+	margs := MergeMaps(l.withFields(toMap(args...)), tInf)
+	zl := l.log()
+	zl.Error().
 		Fields(margs).
 		Err(err).
 		Str("is-fatal", "true").
 		Msg(message)
+	l.flushBeforeExit()
 	exitFunc(1)
 }
 
 func PanicWithContext(spanCtx *trace.SpanContext, err error, message string, args ...KeyValue) {
 	tInf := traceInfo(spanCtx)
-	margs := MergeMaps(toMap(args...), tInf)
-	logger.Panic().
+	l := Default()
+	defer l.flushBeforeExit()
+	margs := MergeMaps(l.withFields(toMap(args...)), tInf)
+	zl := l.log()
+	zl.Panic().
 		Fields(margs).
 		Err(err).
 		Msg(message)
@@ -159,64 +181,34 @@ func MergeMaps(m1 map[string]any, m2 map[string]any) map[string]any {
 	return merged
 }
 
-// Debug logs a debug message. Tracing data (if present) is automatically retrieved from the [context.Context].
+// Debug logs a debug message using the default [Logger]. Tracing data (if present) is automatically retrieved from the [context.Context].
 func Debug(ctx context.Context, message string, args ...KeyValue) {
-	fields := MergeMaps(toMap(args...), getTracingAttributes(ctx))
-
-	logger.Debug().
-		Fields(fields).
-		Msg(message)
+	Default().Debug(ctx, message, args...)
 }
 
-// Info logs an info message. Tracing data (if present) is automatically retrieved from the [context.Context].
+// Info logs an info message using the default [Logger]. Tracing data (if present) is automatically retrieved from the [context.Context].
 func Info(ctx context.Context, message string, args ...KeyValue) {
-	fields := MergeMaps(toMap(args...), getTracingAttributes(ctx))
-
-	logger.Info().
-		Fields(fields).
-		Msg(message)
+	Default().Info(ctx, message, args...)
 }
 
-// Warn logs a warning message. Tracing data (if present) is automatically retrieved from the [context.Context].
+// Warn logs a warning message using the default [Logger]. Tracing data (if present) is automatically retrieved from the [context.Context].
 func Warn(ctx context.Context, message string, args ...KeyValue) {
-	fields := MergeMaps(toMap(args...), getTracingAttributes(ctx))
-
-	logger.Warn().
-		Fields(fields).
-		Msg(message)
+	Default().Warn(ctx, message, args...)
 }
 
-// Error logs an error message. Tracing data (if present) is automatically retrieved from the [context.Context].
+// Error logs an error message using the default [Logger]. Tracing data (if present) is automatically retrieved from the [context.Context].
 func Error(ctx context.Context, err error, message string, args ...KeyValue) {
-	fields := MergeMaps(toMap(args...), getTracingAttributes(ctx))
-
-	logger.Error().
-		Fields(fields).
-		Err(err).
-		Str("is-fatal", "false").
-		Msg(message)
+	Default().Error(ctx, err, message, args...)
 }
 
-// Fatal logs a fatal message. Tracing data (if present) is automatically retrieved from the [context.Context].
+// Fatal logs a fatal message using the default [Logger]. Tracing data (if present) is automatically retrieved from the [context.Context].
 func Fatal(ctx context.Context, err error, message string, args ...KeyValue) {
-	fields := MergeMaps(toMap(args...), getTracingAttributes(ctx))
-
-	logger.Error().
-		Fields(fields).
-		Err(err).
-		Str("is-fatal", "true").
-		Msg(message)
-	exitFunc(1)
+	Default().Fatal(ctx, err, message, args...)
 }
 
-// Panic logs a panic
+// Panic logs a panic message using the default [Logger] and then panics.
 func Panic(ctx context.Context, err error, message string, args ...KeyValue) {
-	fields := MergeMaps(toMap(args...), getTracingAttributes(ctx))
-
-	logger.Panic().
-		Fields(fields).
-		Err(err).
-		Msg(message)
+	Default().Panic(ctx, err, message, args...)
 }
 
 // getTracingAttributes retrieves tracing data from [context.Context]
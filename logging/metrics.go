@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/twistingmercury/telemetry/v2/metrics"
+)
+
+// registeredCounter is a counter of the logging package registered with the metrics package the first time it is
+// used, by [metrics.LazyCollectors].
+type registeredCounter struct {
+	collectors *metrics.LazyCollectors
+}
+
+func newRegisteredCounter(opts prometheus.CounterOpts, labels ...string) *registeredCounter {
+	return &registeredCounter{collectors: metrics.NewLazyCollectors(func() []prometheus.Collector {
+		opts := opts
+		opts.Namespace = metrics.Namespace()
+		return []prometheus.Collector{prometheus.NewCounterVec(opts, labels)}
+	})}
+}
+
+// get returns the counter, registering it if required. It returns nil when the metrics package has not been
+// initialized.
+func (c *registeredCounter) get() *prometheus.CounterVec {
+	if collectors := c.collectors.Get(); collectors != nil {
+		return collectors[0].(*prometheus.CounterVec)
+	}
+	return nil
+}
+
+// inc increments the counter with the label values, when the metrics package has been initialized.
+func (c *registeredCounter) inc(values ...string) {
+	if counter := c.get(); counter != nil {
+		counter.WithLabelValues(values...).Inc()
+	}
+}
+
+// levelChanges counts the level changes made by the [LevelHandler], including the reverts, by component and new
+// level. The component is empty for the level of the logger.
+var levelChanges = newRegisteredCounter(prometheus.CounterOpts{
+	Name: "log_level_changes_total",
+	Help: "The total count of log level changes made at runtime, by component and new level",
+}, "component", "level")
+
+// droppedLines counts the lines dropped by the [AsyncWriter]s, because their queue was full or they were closed.
+var droppedLines = newRegisteredCounter(prometheus.CounterOpts{
+	Name: "log_dropped_lines_total",
+	Help: "The total count of log lines dropped by the async writer, by reason",
+}, "reason")
@@ -0,0 +1,168 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the OpenTelemetry logger that records are emitted with.
+const instrumentationName = "github.com/twistingmercury/telemetry/v2/logging"
+
+// otelWriter is a [zerolog.LevelWriter] that converts each JSON log line into an OpenTelemetry log record. The
+// trace and span ids are set as native record fields, and the service, version and environment are carried by the
+// resource of the provider rather than by each record.
+type otelWriter struct {
+	logger otellog.Logger
+}
+
+// newOTelProvider creates the [sdklog.LoggerProvider] that batches records to opts.Exporter.
+func newOTelProvider(opts Options) (*sdklog.LoggerProvider, error) {
+	res, err := resource.New(
+		context.Background(),
+		resource.WithAttributes(opts.ResourceAttributes...),
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String(opts.ServiceName),
+			semconv.ServiceVersionKey.String(opts.ServiceVersion),
+			semconv.DeploymentEnvironmentKey.String(opts.Environment),
+		))
+	if err != nil {
+		return nil, err
+	}
+
+	return sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(opts.Exporter)),
+	), nil
+}
+
+// Write emits the log line p as an info record. zerolog calls WriteLevel instead whenever the level is known.
+func (w *otelWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.InfoLevel, p)
+}
+
+// WriteLevel emits the log line p with the severity of level.
+func (w *otelWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return 0, err
+	}
+
+	var rec otellog.Record
+	rec.SetObservedTimestamp(time.Now())
+	rec.SetSeverity(toOTelSeverity(level))
+	rec.SetSeverityText(level.String())
+
+	if ts, ok := fields[zerolog.TimestampFieldName].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			rec.SetTimestamp(t)
+		}
+	}
+	if msg, ok := fields[zerolog.MessageFieldName].(string); ok {
+		rec.SetBody(otellog.StringValue(msg))
+	}
+
+	ctx := contextWithSpanContext(fields)
+	for _, key := range []string{
+		zerolog.LevelFieldName, zerolog.MessageFieldName, zerolog.TimestampFieldName,
+		"service", "version", "environment", TraceIDAttr, SpanIDAttr,
+	} {
+		delete(fields, key)
+	}
+
+	for k, v := range fields {
+		rec.AddAttributes(otellog.KeyValue{Key: k, Value: toOTelValue(v)})
+	}
+
+	w.logger.Emit(ctx, rec)
+	return len(p), nil
+}
+
+// contextWithSpanContext returns a context carrying the span context described by the trace and span id fields,
+// so the SDK sets them as the native trace fields of the record.
+func contextWithSpanContext(fields map[string]any) context.Context {
+	ctx := context.Background()
+
+	tid, _ := fields[TraceIDAttr].(string)
+	sid, _ := fields[SpanIDAttr].(string)
+	traceID, err := trace.TraceIDFromHex(tid)
+	if err != nil {
+		return ctx
+	}
+	spanID, err := trace.SpanIDFromHex(sid)
+	if err != nil {
+		return ctx
+	}
+
+	return trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+}
+
+// toOTelSeverity maps a [zerolog.Level] to an [otellog.Severity].
+func toOTelSeverity(level zerolog.Level) otellog.Severity {
+	switch level {
+	case zerolog.TraceLevel:
+		return otellog.SeverityTrace
+	case zerolog.DebugLevel:
+		return otellog.SeverityDebug
+	case zerolog.InfoLevel:
+		return otellog.SeverityInfo
+	case zerolog.WarnLevel:
+		return otellog.SeverityWarn
+	case zerolog.ErrorLevel:
+		return otellog.SeverityError
+	case zerolog.FatalLevel:
+		return otellog.SeverityFatal
+	case zerolog.PanicLevel:
+		return otellog.SeverityFatal4
+	default:
+		return otellog.SeverityUndefined
+	}
+}
+
+// toOTelValue converts a value decoded from a JSON log line into an [otellog.Value].
+func toOTelValue(v any) otellog.Value {
+	switch val := v.(type) {
+	case nil:
+		return otellog.Value{}
+	case string:
+		return otellog.StringValue(val)
+	case bool:
+		return otellog.BoolValue(val)
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return otellog.Int64Value(i)
+		}
+		f, _ := val.Float64()
+		return otellog.Float64Value(f)
+	case []any:
+		values := make([]otellog.Value, 0, len(val))
+		for _, item := range val {
+			values = append(values, toOTelValue(item))
+		}
+		return otellog.SliceValue(values...)
+	case map[string]any:
+		kvs := make([]otellog.KeyValue, 0, len(val))
+		for k, item := range val {
+			kvs = append(kvs, otellog.KeyValue{Key: k, Value: toOTelValue(item)})
+		}
+		return otellog.MapValue(kvs...)
+	default:
+		return otellog.StringValue(fmt.Sprint(val))
+	}
+}
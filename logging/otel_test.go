@@ -0,0 +1,182 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpReceiver is an in-process stub of an OTLP/HTTP logs receiver.
+type otlpReceiver struct {
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+}
+
+func (r *otlpReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	msg := &collogspb.ExportLogsServiceRequest{}
+	if err := proto.Unmarshal(body, msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	r.requests = append(r.requests, msg)
+	r.mu.Unlock()
+
+	resp, _ := proto.Marshal(&collogspb.ExportLogsServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(resp)
+}
+
+// records returns the resource logs and log records received so far.
+func (r *otlpReceiver) records() ([]*logspb.ResourceLogs, []*logspb.LogRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var resources []*logspb.ResourceLogs
+	var records []*logspb.LogRecord
+	for _, req := range r.requests {
+		for _, rl := range req.ResourceLogs {
+			resources = append(resources, rl)
+			for _, sl := range rl.ScopeLogs {
+				records = append(records, sl.LogRecords...)
+			}
+		}
+	}
+	return resources, records
+}
+
+func newOTLPExporter(t *testing.T, receiver *otlpReceiver) *otlploghttp.Exporter {
+	t.Helper()
+
+	srv := httptest.NewServer(receiver)
+	t.Cleanup(srv.Close)
+
+	exporter, err := otlploghttp.New(context.Background(),
+		otlploghttp.WithEndpoint(strings.TrimPrefix(srv.URL, "http://")),
+		otlploghttp.WithInsecure())
+	require.NoError(t, err)
+	return exporter
+}
+
+func attributeMap(attrs []*commonpb.KeyValue) map[string]*commonpb.AnyValue {
+	m := make(map[string]*commonpb.AnyValue, len(attrs))
+	for _, kv := range attrs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestOTLPExport(t *testing.T) {
+	receiver := &otlpReceiver{}
+	var buf bytes.Buffer
+
+	l, err := logging.New(logging.Options{
+		Level:          zerolog.DebugLevel,
+		Writer:         &buf,
+		Exporter:       newOTLPExporter(t, receiver),
+		ServiceName:    serviceName,
+		ServiceVersion: serviceVersion,
+		Environment:    environment,
+	})
+	require.NoError(t, err)
+
+	traceID := trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10}
+	spanID := trace.SpanID{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	l.Info(ctx, "Info message", logging.KeyValue{Key: "key1", Value: "value1"}, logging.KeyValue{Key: "key2", Value: 123})
+	l.Error(context.Background(), errors.New("test error"), "Error message")
+	require.NoError(t, l.Shutdown(context.Background()))
+	assert.NoError(t, l.Shutdown(context.Background()), "Shutdown should be safe to call more than once")
+
+	assert.Len(t, decodeLines(t, &buf), 2, "messages should still be written to the writer")
+
+	resources, records := receiver.records()
+	require.NotEmpty(t, resources)
+	res := attributeMap(resources[0].Resource.Attributes)
+	assert.Equal(t, serviceName, res["service.name"].GetStringValue())
+	assert.Equal(t, serviceVersion, res["service.version"].GetStringValue())
+	assert.Equal(t, environment, res["deployment.environment"].GetStringValue())
+
+	require.Len(t, records, 2)
+
+	info := records[0]
+	assert.Equal(t, "Info message", info.Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, info.SeverityNumber)
+	assert.Equal(t, "info", info.SeverityText)
+	assert.Equal(t, traceID[:], info.TraceId)
+	assert.Equal(t, spanID[:], info.SpanId)
+	assert.NotZero(t, info.TimeUnixNano)
+	attrs := attributeMap(info.Attributes)
+	assert.Equal(t, "value1", attrs["key1"].GetStringValue())
+	assert.Equal(t, int64(123), attrs["key2"].GetIntValue())
+	for _, key := range []string{"service", "version", "environment", "message", "level", "time", logging.TraceIDAttr, logging.SpanIDAttr} {
+		assert.NotContains(t, attrs, key, "%s should not be exported as an attribute", key)
+	}
+
+	errRec := records[1]
+	assert.Equal(t, "Error message", errRec.Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, errRec.SeverityNumber)
+	assert.Empty(t, errRec.TraceId)
+	assert.Equal(t, "test error", attributeMap(errRec.Attributes)["error"].GetStringValue())
+}
+
+func TestOTLPExportWithoutWriter(t *testing.T) {
+	receiver := &otlpReceiver{}
+
+	l, err := logging.New(logging.Options{
+		Level:       zerolog.InfoLevel,
+		Exporter:    newOTLPExporter(t, receiver),
+		ServiceName: serviceName,
+	})
+	require.NoError(t, err)
+
+	child := l.With(logging.KeyValue{Key: "component", Value: "db"})
+	child.Debug(context.Background(), "Debug message")
+	child.Warn(context.Background(), "Warn message")
+	require.NoError(t, l.ForceFlush(context.Background()))
+
+	_, records := receiver.records()
+	require.Len(t, records, 1)
+	assert.Equal(t, "Warn message", records[0].Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, records[0].SeverityNumber)
+	assert.Equal(t, "db", attributeMap(records[0].Attributes)["component"].GetStringValue())
+
+	require.NoError(t, l.Shutdown(context.Background()))
+}
+
+func TestShutdownWithoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	err := logging.Initialize(zerolog.DebugLevel, &buf, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+
+	assert.NoError(t, logging.Shutdown(context.Background()))
+}
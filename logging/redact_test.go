@@ -0,0 +1,53 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/redact"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestRedaction(t *testing.T) {
+	defer logging.SetDefault(logging.Default())
+
+	policy, err := redact.New(redact.Options{
+		Keys:      []string{"password", "token"},
+		KeyAction: redact.ActionDrop,
+		Matchers:  []redact.Matcher{redact.Email(redact.ActionMask)},
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	l, err := logging.New(logging.Options{Level: zerolog.DebugLevel, Writer: &buf, Redaction: policy})
+	require.NoError(t, err)
+	logging.SetDefault(l)
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "login")
+	defer span.End()
+	ctx = logging.ContextWithFields(ctx, logging.KeyValue{Key: "token", Value: "abc"})
+
+	l.Info(ctx, "login", logging.KeyValue{Key: "user", Value: "jane@example.com"}, logging.KeyValue{Key: "password", Value: "hunter2"})
+	l.With(logging.KeyValue{Key: "user.password", Value: "hunter2"}).Warn(ctx, "retry")
+	slog.New(logging.NewSlogHandler()).WithGroup("request").InfoContext(ctx, "slog", "password", "hunter2", "email", "jane@example.com")
+	spanCtx := span.SpanContext()
+	logging.InfoWithContext(&spanCtx, "deprecated", logging.KeyValue{Key: "password", Value: "hunter2"})
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 4)
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.NotContains(t, buf.String(), "jane@example.com")
+	assert.NotContains(t, buf.String(), `"token"`, "the context fields should be redacted")
+
+	assert.Equal(t, redact.Mask, lines[0]["user"])
+	assert.Equal(t, spanCtx.TraceID().String(), lines[0][logging.TraceIDAttr], "the tracing data should not be redacted")
+	assert.Equal(t, map[string]any{"email": redact.Mask}, lines[2]["request"])
+	assert.Equal(t, "deprecated", lines[3]["message"])
+}
@@ -0,0 +1,251 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// defaultSamplingInterval is the default of [SamplingOptions.Interval].
+	defaultSamplingInterval = time.Second
+	// defaultSummaryInterval is the default of [SamplingOptions.SummaryInterval].
+	defaultSummaryInterval = time.Minute
+	// defaultSamplingFirst and defaultSamplingThereafter are the default of [SamplingOptions.SamplingRate].
+	defaultSamplingFirst      = 100
+	defaultSamplingThereafter = 100
+	// maxSummaryMessages is the number of messages, with the most suppressed, listed by the summary.
+	maxSummaryMessages = 10
+	// maxSampleKeys is the number of distinct messages counted in an interval. The messages beyond it are written
+	// without being counted, so a service logging unique messages cannot grow the counts without bound.
+	maxSampleKeys = 10000
+)
+
+// SamplingRate is how many messages with the same level and text are written in each sampling interval.
+type SamplingRate struct {
+	// First is the number of messages written in each interval before sampling starts.
+	First int
+	// Thereafter is the sampling rate after the first messages: one in every Thereafter messages is written. Zero
+	// suppresses all of them until the next interval.
+	Thereafter int
+}
+
+// SamplingOptions configure the sampling of a [Logger], which limits how many messages with the same level and text
+// are written in each interval, so a hot path logging on every call cannot drown the log pipeline. The number of
+// messages suppressed is written periodically in a summary message.
+type SamplingOptions struct {
+	// Interval is the period the messages are counted over. The default is one second.
+	Interval time.Duration
+	// SamplingRate is the rate of every level that has no rate in Levels. The default is the first 100 messages, and
+	// then one in every 100.
+	SamplingRate
+	// Levels are the rates of specific levels, such as a lower rate for debug messages.
+	Levels map[zerolog.Level]SamplingRate
+	// SampleErrors also samples the error messages. By default, error, fatal and panic messages are always written.
+	// Fatal and panic messages are never sampled.
+	SampleErrors bool
+	// SummaryInterval is the longest time between the suppression of a message and the summary message counting it.
+	// The default is one minute.
+	SummaryInterval time.Duration
+}
+
+// sampleKey identifies the messages counted together.
+type sampleKey struct {
+	level     zerolog.Level
+	component string
+	message   string
+}
+
+// sampler decides which messages are written, and writes the summary of the messages it suppressed. It is shared by
+// all the loggers created from the same [New] call.
+type sampler struct {
+	opts SamplingOptions
+	// summary writes the summary message.
+	summary func(summary samplingSummary)
+
+	mu sync.Mutex
+	// counts are the numbers of messages of each key since windowStart. They are replaced by an empty map when the
+	// interval ends, so the keys of the messages that are not logged again are forgotten.
+	counts      map[sampleKey]int
+	windowStart time.Time
+	// suppressed counts the messages suppressed since the last summary.
+	suppressed samplingSummary
+	// timer writes the next summary, and is nil when no message was suppressed since the last one.
+	timer *time.Timer
+	// closed is set by close, after which no summary is written, as the writer of the logger may be closed.
+	closed bool
+}
+
+// newSampler returns a sampler configured by opts, or an error when opts are not valid.
+func newSampler(opts SamplingOptions, summary func(samplingSummary)) (*sampler, error) {
+	if opts.Interval < 0 {
+		return nil, fmt.Errorf("invalid sampling interval: `%s`", opts.Interval)
+	}
+	if opts.SummaryInterval < 0 {
+		return nil, fmt.Errorf("invalid sampling summary interval: `%s`", opts.SummaryInterval)
+	}
+	if opts.First < 0 || opts.Thereafter < 0 {
+		return nil, fmt.Errorf("invalid sampling rate: first %d, thereafter %d", opts.First, opts.Thereafter)
+	}
+	for level, rate := range opts.Levels {
+		if rate.First < 0 || rate.Thereafter < 0 {
+			return nil, fmt.Errorf("invalid sampling rate of level %s: first %d, thereafter %d", level, rate.First, rate.Thereafter)
+		}
+	}
+
+	if opts.Interval == 0 {
+		opts.Interval = defaultSamplingInterval
+	}
+	if opts.SummaryInterval == 0 {
+		opts.SummaryInterval = defaultSummaryInterval
+	}
+	if opts.SamplingRate == (SamplingRate{}) {
+		opts.SamplingRate = SamplingRate{First: defaultSamplingFirst, Thereafter: defaultSamplingThereafter}
+	}
+	return &sampler{
+		opts:       opts,
+		summary:    summary,
+		counts:     make(map[sampleKey]int),
+		suppressed: newSamplingSummary(),
+	}, nil
+}
+
+// allow counts a message and reports whether it is written.
+func (s *sampler) allow(level zerolog.Level, component, message string) bool {
+	if level >= zerolog.FatalLevel || (level == zerolog.ErrorLevel && !s.opts.SampleErrors) {
+		return true
+	}
+	rate, ok := s.opts.Levels[level]
+	if !ok {
+		rate = s.opts.SamplingRate
+	}
+
+	key := sampleKey{level: level, component: component, message: message}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.windowStart) >= s.opts.Interval {
+		s.counts = make(map[sampleKey]int, len(s.counts))
+		s.windowStart = now
+	}
+	n, ok := s.counts[key]
+	if !ok && len(s.counts) >= maxSampleKeys {
+		return true
+	}
+	n++
+	s.counts[key] = n
+
+	if n <= rate.First || (rate.Thereafter > 0 && (n-rate.First)%rate.Thereafter == 0) {
+		return true
+	}
+
+	s.suppressed.add(key)
+	if s.timer == nil && !s.closed {
+		s.timer = time.AfterFunc(s.opts.SummaryInterval, s.flush)
+	}
+	return false
+}
+
+// flush writes the summary of the messages suppressed since the last one, if any. The summary is written with the
+// lock held, so it cannot be written after close returns.
+func (s *sampler) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked()
+}
+
+// close writes the last summary and stops writing them: the messages suppressed afterwards are not summarized.
+func (s *sampler) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked()
+	s.closed = true
+}
+
+// flushLocked writes the summary unless the sampler is closed. s.mu must be held.
+func (s *sampler) flushLocked() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if s.closed {
+		return
+	}
+	suppressed := s.suppressed
+	s.suppressed = newSamplingSummary()
+	if suppressed.total > 0 {
+		s.summary(suppressed)
+	}
+}
+
+// samplingSummary counts the suppressed messages, in total, by level, and by key for at most maxSampleKeys keys.
+type samplingSummary struct {
+	total    int
+	byLevel  map[zerolog.Level]int
+	messages map[sampleKey]int
+}
+
+func newSamplingSummary() samplingSummary {
+	return samplingSummary{byLevel: make(map[zerolog.Level]int), messages: make(map[sampleKey]int)}
+}
+
+// add counts a suppressed message.
+func (s *samplingSummary) add(key sampleKey) {
+	s.total++
+	s.byLevel[key.level]++
+	if _, ok := s.messages[key]; ok || len(s.messages) < maxSampleKeys {
+		s.messages[key]++
+	}
+}
+
+// writeSamplingSummary writes the summary of the suppressed messages at the warn level, whatever the level of l: the
+// total, the totals by level, and the messages with the most suppressed.
+func (l *Logger) writeSamplingSummary(summary samplingSummary) {
+	byLevel := make(map[string]int, len(summary.byLevel))
+	for level, n := range summary.byLevel {
+		byLevel[level.String()] = n
+	}
+
+	suppressed := summary.messages
+	keys := make([]sampleKey, 0, len(suppressed))
+	for key := range suppressed {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if suppressed[keys[i]] != suppressed[keys[j]] {
+			return suppressed[keys[i]] > suppressed[keys[j]]
+		}
+		return keys[i].message < keys[j].message
+	})
+
+	messages := make(map[string]int)
+	for _, key := range keys[:min(len(keys), maxSummaryMessages)] {
+		name := key.level.String() + ": " + key.message
+		if key.component != "" {
+			name = key.level.String() + " [" + key.component + "]: " + key.message
+		}
+		messages[name] += suppressed[key]
+	}
+
+	zl := l.zl.Level(zerolog.TraceLevel)
+	zl.Warn().
+		Fields(l.fields).
+		Int("suppressed", summary.total).
+		Interface("suppressed_by_level", byLevel).
+		Interface("suppressed_messages", messages).
+		Msg("log messages suppressed by sampling")
+}
+
+// sampled reports whether a message at level is written by l, counting it when l is sampled. The messages below the
+// level of l are not counted, as they are not written anyway.
+func (l *Logger) sampled(level zerolog.Level, message string) bool {
+	if l.sampler == nil || level < l.level.get() || level < zerolog.GlobalLevel() {
+		return true
+	}
+	return l.sampler.allow(level, l.component, message)
+}
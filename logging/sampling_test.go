@@ -0,0 +1,254 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+)
+
+func newSampledLogger(t *testing.T, buf *bytes.Buffer, opts logging.SamplingOptions) *logging.Logger {
+	t.Helper()
+	l, err := logging.New(logging.Options{Level: zerolog.DebugLevel, Writer: buf, Sampling: &opts})
+	require.NoError(t, err)
+	return l
+}
+
+// countMessages returns how many lines have each message.
+func countMessages(t *testing.T, buf *bytes.Buffer) map[string]int {
+	t.Helper()
+	counts := make(map[string]int)
+	for _, line := range decodeLines(t, buf) {
+		counts[line["message"].(string)]++
+	}
+	return counts
+}
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := context.Background()
+	l := newSampledLogger(t, &buf, logging.SamplingOptions{
+		Interval:     time.Hour,
+		SamplingRate: logging.SamplingRate{First: 2, Thereafter: 3},
+	})
+
+	for i := 0; i < 10; i++ {
+		l.Warn(ctx, "hot path")
+		l.Info(ctx, "hot path")
+	}
+	l.Warn(ctx, "cold path")
+	l.Component("db").Warn(ctx, "hot path")
+
+	counts := countMessages(t, &buf)
+	assert.Equal(t, 9, counts["hot path"], "the 1st, 2nd, 5th and 8th messages of each level, and the db component, should be written")
+	assert.Equal(t, 1, counts["cold path"])
+}
+
+func TestSamplingLevels(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := context.Background()
+	l := newSampledLogger(t, &buf, logging.SamplingOptions{
+		Interval:     time.Hour,
+		SamplingRate: logging.SamplingRate{First: 100},
+		Levels:       map[zerolog.Level]logging.SamplingRate{zerolog.DebugLevel: {First: 1}},
+	})
+
+	for i := 0; i < 5; i++ {
+		l.Debug(ctx, "debug")
+		l.Info(ctx, "info")
+		l.Error(ctx, errors.New("boom"), "error")
+	}
+
+	assert.Equal(t, map[string]int{"debug": 1, "info": 5, "error": 5}, countMessages(t, &buf), "errors should not be sampled by default")
+}
+
+func TestSamplingErrors(t *testing.T) {
+	var buf bytes.Buffer
+	l := newSampledLogger(t, &buf, logging.SamplingOptions{
+		Interval:     time.Hour,
+		SamplingRate: logging.SamplingRate{First: 1},
+		SampleErrors: true,
+	})
+
+	for i := 0; i < 3; i++ {
+		l.Error(context.Background(), errors.New("boom"), "error")
+	}
+	for i := 0; i < 2; i++ {
+		assert.Panics(t, func() { l.Panic(context.Background(), errors.New("boom"), "panic") })
+	}
+
+	counts := countMessages(t, &buf)
+	assert.Equal(t, 1, counts["error"])
+	assert.Equal(t, 2, counts["panic"], "panic messages should never be sampled")
+}
+
+func TestSamplingInterval(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := context.Background()
+	l := newSampledLogger(t, &buf, logging.SamplingOptions{
+		Interval:     50 * time.Millisecond,
+		SamplingRate: logging.SamplingRate{First: 1},
+	})
+
+	l.Info(ctx, "tick")
+	l.Info(ctx, "tick")
+	time.Sleep(60 * time.Millisecond)
+	l.Info(ctx, "tick")
+
+	assert.Equal(t, 2, countMessages(t, &buf)["tick"], "the counts should be reset every interval")
+}
+
+func TestSamplingBelowLevel(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := context.Background()
+	l := newSampledLogger(t, &buf, logging.SamplingOptions{Interval: time.Hour, SamplingRate: logging.SamplingRate{First: 1}})
+	l.SetLevel(zerolog.InfoLevel)
+
+	l.Debug(ctx, "message")
+	l.SetLevel(zerolog.DebugLevel)
+	l.Debug(ctx, "message")
+
+	assert.Equal(t, 1, countMessages(t, &buf)["message"], "the messages below the level should not be counted")
+}
+
+func TestSamplingUniqueMessages(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := context.Background()
+	l := newSampledLogger(t, &buf, logging.SamplingOptions{Interval: time.Hour, SamplingRate: logging.SamplingRate{First: 1}})
+
+	for i := 0; i < logging.MaxSampleKeys+100; i++ {
+		l.Info(ctx, fmt.Sprintf("order %d placed", i))
+	}
+	assert.Equal(t, logging.MaxSampleKeys, logging.SampledKeys(l), "the number of counted messages should be capped")
+	assert.Len(t, decodeLines(t, &buf), logging.MaxSampleKeys+100, "the messages beyond the cap should be written")
+
+	buf.Reset()
+	l = newSampledLogger(t, &buf, logging.SamplingOptions{Interval: 50 * time.Millisecond})
+	for i := 0; i < 100; i++ {
+		l.Info(ctx, fmt.Sprintf("order %d placed", i))
+	}
+	time.Sleep(60 * time.Millisecond)
+	l.Info(ctx, "order placed")
+	assert.Equal(t, 1, logging.SampledKeys(l), "the messages of the previous interval should be forgotten")
+}
+
+func TestSamplingSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := newSampledLogger(t, &buf, logging.SamplingOptions{Interval: time.Hour, SamplingRate: logging.SamplingRate{First: 1}})
+
+	log := slog.New(l.SlogHandler())
+	log.Info("slog")
+	log.Info("slog")
+
+	assert.Equal(t, 1, countMessages(t, &buf)["slog"])
+}
+
+func TestSamplingSummary(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := context.Background()
+	l := newSampledLogger(t, &buf, logging.SamplingOptions{
+		Interval:        time.Hour,
+		SamplingRate:    logging.SamplingRate{First: 1},
+		SummaryInterval: time.Hour,
+	})
+	l.SetLevel(zerolog.ErrorLevel)
+	l.SetComponentLevel("db", zerolog.DebugLevel)
+
+	db := l.Component("db")
+	for i := 0; i < 4; i++ {
+		db.Warn(ctx, "slow query")
+		db.Debug(ctx, "query")
+	}
+	require.NoError(t, l.ForceFlush(ctx))
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 3)
+	summary := lines[2]
+	assert.Equal(t, "log messages suppressed by sampling", summary["message"])
+	assert.Equal(t, "warn", summary["level"], "the summary should be written whatever the level")
+	assert.Equal(t, float64(6), summary["suppressed"])
+	assert.Equal(t, map[string]any{"warn": float64(3), "debug": float64(3)}, summary["suppressed_by_level"])
+	assert.Equal(t, map[string]any{"warn [db]: slow query": float64(3), "debug [db]: query": float64(3)}, summary["suppressed_messages"])
+
+	buf.Reset()
+	require.NoError(t, l.ForceFlush(ctx))
+	assert.Empty(t, buf.String(), "no summary should be written when nothing was suppressed")
+}
+
+func TestSamplingSummaryInterval(t *testing.T) {
+	var buf lockedBuffer
+	l, err := logging.New(logging.Options{
+		Level:  zerolog.DebugLevel,
+		Writer: &buf,
+		Sampling: &logging.SamplingOptions{
+			Interval:        time.Hour,
+			SamplingRate:    logging.SamplingRate{Thereafter: 2},
+			SummaryInterval: 20 * time.Millisecond,
+		},
+	})
+	require.NoError(t, err)
+
+	l.Info(context.Background(), "suppressed")
+	l.Info(context.Background(), "suppressed")
+	assert.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), `"suppressed":1`)
+	}, time.Second, 5*time.Millisecond, "the summary should be written after the summary interval")
+}
+
+func TestSamplingShutdown(t *testing.T) {
+	var buf lockedBuffer
+	l, err := logging.New(logging.Options{
+		Level:  zerolog.DebugLevel,
+		Writer: &buf,
+		Sampling: &logging.SamplingOptions{
+			Interval:        time.Hour,
+			SamplingRate:    logging.SamplingRate{Thereafter: 2},
+			SummaryInterval: 10 * time.Millisecond,
+		},
+	})
+	require.NoError(t, err)
+
+	l.Info(context.Background(), "suppressed")
+	l.Info(context.Background(), "suppressed")
+	require.NoError(t, l.Shutdown(context.Background()))
+	require.Contains(t, buf.String(), `"suppressed":1`, "Shutdown should write the summary")
+
+	written := buf.String()
+	l.Info(context.Background(), "suppressed")
+	l.Info(context.Background(), "suppressed")
+	time.Sleep(50 * time.Millisecond)
+	assert.NotContains(t, buf.String()[len(written):], "log messages suppressed by sampling",
+		"no summary should be written after Shutdown")
+}
+
+func TestSamplingDefaultRate(t *testing.T) {
+	var buf bytes.Buffer
+	l := newSampledLogger(t, &buf, logging.SamplingOptions{Interval: time.Hour})
+
+	for i := 0; i < 300; i++ {
+		l.Info(context.Background(), "busy")
+	}
+	assert.Equal(t, 102, countMessages(t, &buf)["busy"], "the first 100 messages, and then one in every 100, should be written")
+}
+
+func TestSamplingInvalidOptions(t *testing.T) {
+	for _, opts := range []logging.SamplingOptions{
+		{Interval: -time.Second},
+		{SummaryInterval: -time.Second},
+		{SamplingRate: logging.SamplingRate{First: -1}},
+		{Levels: map[zerolog.Level]logging.SamplingRate{zerolog.DebugLevel: {Thereafter: -1}}},
+	} {
+		opts := opts
+		_, err := logging.New(logging.Options{Writer: &bytes.Buffer{}, Sampling: &opts})
+		assert.Error(t, err, "%+v", opts)
+	}
+}
@@ -0,0 +1,210 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// slogHandler is a [slog.Handler] that writes records using a [Logger]. When logger is nil the default
+// logger is used, so the handler follows any later call to [Initialize].
+type slogHandler struct {
+	logger *Logger
+	fields map[string]any
+	groups []string
+}
+
+// NewSlogHandler returns a [slog.Handler] that writes records through the logging package. Records have the same
+// JSON shape as the package functions: service, version, environment and timestamp, plus the trace id and span id
+// found in the [context.Context] passed to the [slog.Logger].
+func NewSlogHandler() slog.Handler {
+	return &slogHandler{fields: make(map[string]any)}
+}
+
+// SlogHandler returns a [slog.Handler] that writes records through l, including any fields added with [Logger.With].
+func (l *Logger) SlogHandler() slog.Handler {
+	return &slogHandler{logger: l, fields: make(map[string]any)}
+}
+
+// SetSlogDefault installs a handler created by [NewSlogHandler] as the handler used by [slog.Default].
+func SetSlogDefault() {
+	slog.SetDefault(slog.New(NewSlogHandler()))
+}
+
+// Enabled reports whether the level is enabled by the level of the [Logger].
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	zl := toZerologLevel(level)
+	return zl >= zerolog.GlobalLevel() && zl >= h.target().Level()
+}
+
+// Handle writes the record with its time. Attributes are nested under any groups opened with WithGroup, while the fields added
+// by [ContextWithFields] and the tracing data are always written at the top level. The fields are redacted by the
+// [Options.Redaction] policy of the [Logger].
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	l := h.target()
+	if !l.sampled(toZerologLevel(r.Level), r.Message) {
+		return nil
+	}
+
+	fields := copyFields(h.fields)
+	if r.NumAttrs() > 0 {
+		target := groupFields(fields, h.groups)
+		r.Attrs(func(a slog.Attr) bool {
+			addAttr(target, a)
+			return true
+		})
+	}
+
+	fields = l.withFields(MergeMaps(toMap(FieldsFromContext(ctx)...), fields))
+	zl := l.log()
+	e := zl.WithLevel(toZerologLevel(r.Level))
+	if !r.Time.IsZero() {
+		e = e.Ctx(context.WithValue(ctx, recordTimeKey{}, r.Time))
+	}
+	e.Fields(MergeMaps(fields, getTracingAttributes(ctx))).
+		Msg(r.Message)
+	return nil
+}
+
+// WithAttrs returns a handler that adds attrs to every record, nested under the groups opened so far.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	fields := copyFields(h.fields)
+	target := groupFields(fields, h.groups)
+	for _, a := range attrs {
+		addAttr(target, a)
+	}
+	return &slogHandler{logger: h.logger, fields: fields, groups: h.groups}
+}
+
+// WithGroup returns a handler that nests all subsequent attributes under name.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	groups := make([]string, len(h.groups), len(h.groups)+1)
+	copy(groups, h.groups)
+	return &slogHandler{logger: h.logger, fields: h.fields, groups: append(groups, name)}
+}
+
+// target returns the [Logger] records are written to.
+func (h *slogHandler) target() *Logger {
+	if h.logger == nil {
+		return Default()
+	}
+	return h.logger.resolve()
+}
+
+// toZerologLevel maps a [slog.Level] to the closest [zerolog.Level] that does not exceed it.
+func toZerologLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return zerolog.TraceLevel
+	case level < slog.LevelInfo:
+		return zerolog.DebugLevel
+	case level < slog.LevelWarn:
+		return zerolog.InfoLevel
+	case level < slog.LevelError:
+		return zerolog.WarnLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
+
+// addAttr adds a resolved attribute to m, following the [slog.Handler] rules for empty attributes and groups.
+func addAttr(m map[string]any, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		m[a.Key] = slogValue(a.Value)
+		return
+	}
+
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+
+	// an unnamed group is inlined into its parent
+	target := m
+	if a.Key != "" {
+		target = groupFields(m, []string{a.Key})
+	}
+	for _, ga := range attrs {
+		addAttr(target, ga)
+	}
+}
+
+// slogValue converts a resolved, non-group [slog.Value] into a value zerolog can write.
+func slogValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration()
+	case slog.KindTime:
+		return v.Time()
+	default:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+		return v.Any()
+	}
+}
+
+// groupFields returns the map nested in m under the path of groups, creating it if it does not exist.
+func groupFields(m map[string]any, groups []string) map[string]any {
+	for _, g := range groups {
+		child, ok := m[g].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			m[g] = child
+		}
+		m = child
+	}
+	return m
+}
+
+// copyFields returns a deep copy of the nested group maps in m so handlers never share mutable state.
+func copyFields(m map[string]any) map[string]any {
+	c := make(map[string]any, len(m))
+	for k, v := range m {
+		if child, ok := v.(map[string]any); ok {
+			v = copyFields(child)
+		}
+		c[k] = v
+	}
+	return c
+}
+
+// recordTimeKey is the key of the time of a [slog.Record] in the context of the event that writes it.
+type recordTimeKey struct{}
+
+// timestampHook adds the time of every message: the time of the [slog.Record] it writes, if any, or the current time.
+type timestampHook struct{}
+
+// Run adds the time field to e.
+func (timestampHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	if t, ok := e.GetCtx().Value(recordTimeKey{}).(time.Time); ok {
+		e.Time(zerolog.TimestampFieldName, t)
+		return
+	}
+	e.Timestamp()
+}
@@ -0,0 +1,174 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m), line)
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	err := logging.Initialize(zerolog.DebugLevel, &buf, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+
+	log := slog.New(logging.NewSlogHandler())
+	log.Info("slog message", "key1", "value1", slog.Int("key2", 123), slog.Bool("key3", true))
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	line := lines[0]
+	assert.Equal(t, "info", line["level"])
+	assert.Equal(t, "slog message", line["message"])
+	assert.Equal(t, serviceName, line["service"])
+	assert.Equal(t, serviceVersion, line["version"])
+	assert.Equal(t, environment, line["environment"])
+	assert.Contains(t, line, "time")
+	assert.Equal(t, "value1", line["key1"])
+	assert.Equal(t, float64(123), line["key2"])
+	assert.Equal(t, true, line["key3"])
+	assert.NotContains(t, line, logging.TraceIDAttr)
+	assert.NotContains(t, line, logging.SpanIDAttr)
+}
+
+func TestSlogHandlerRecordTime(t *testing.T) {
+	var buf bytes.Buffer
+	err := logging.Initialize(zerolog.DebugLevel, &buf, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+
+	recorded := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	h := logging.NewSlogHandler()
+	require.NoError(t, h.Handle(context.Background(), slog.NewRecord(recorded, slog.LevelInfo, "recorded", 0)))
+	require.NoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "no time", 0)))
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, recorded.Format(zerolog.TimeFieldFormat), lines[0]["time"], "the time of the record should be written")
+	written, err := time.Parse(zerolog.TimeFieldFormat, lines[1]["time"].(string))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), written, time.Minute, "a record without a time should use the current time")
+}
+
+func TestSlogHandlerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	err := logging.Initialize(zerolog.InfoLevel, &buf, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+
+	h := logging.NewSlogHandler()
+	ctx := context.Background()
+	assert.False(t, h.Enabled(ctx, slog.LevelDebug))
+	assert.True(t, h.Enabled(ctx, slog.LevelInfo))
+
+	log := slog.New(h)
+	log.Debug("debug message")
+	log.Info("info message")
+	log.Warn("warn message")
+	log.Error("error message")
+	log.Log(ctx, slog.LevelError+4, "above error message")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 4)
+	assert.Equal(t, "info", lines[0]["level"])
+	assert.Equal(t, "warn", lines[1]["level"])
+	assert.Equal(t, "error", lines[2]["level"])
+	assert.Equal(t, "error", lines[3]["level"])
+}
+
+func TestSlogHandlerGroupsAndAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	err := logging.Initialize(zerolog.DebugLevel, &buf, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+
+	base := slog.New(logging.NewSlogHandler()).With("component", "db")
+	grouped := base.WithGroup("request").With("id", "abc")
+
+	grouped.Info("grouped message",
+		slog.Int("status", 200),
+		slog.Group("user", slog.String("name", "scooby")),
+		slog.Group("", slog.String("inlined", "yes")),
+		slog.Group("empty"),
+		slog.Attr{})
+	base.Info("base message")
+	base.WithGroup("unused").Info("no attrs message")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 3)
+
+	assert.Equal(t, "db", lines[0]["component"])
+	assert.Equal(t, map[string]any{
+		"id":      "abc",
+		"status":  float64(200),
+		"user":    map[string]any{"name": "scooby"},
+		"inlined": "yes",
+	}, lines[0]["request"])
+	assert.NotContains(t, lines[0], "")
+
+	assert.Equal(t, "db", lines[1]["component"])
+	assert.NotContains(t, lines[1], "request", "attributes from a derived handler must not leak into its parent")
+
+	assert.NotContains(t, lines[2], "unused", "empty groups should be omitted")
+}
+
+func TestSlogHandlerWithTracingDataInContext(t *testing.T) {
+	var buf bytes.Buffer
+	err := logging.Initialize(zerolog.DebugLevel, &buf, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+
+	traceID := trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10}
+	spanID := trace.SpanID{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18}
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+
+	slog.New(logging.NewSlogHandler()).WithGroup("group").InfoContext(ctx, "traced message", "key", "value")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, traceID.String(), lines[0][logging.TraceIDAttr])
+	assert.Equal(t, spanID.String(), lines[0][logging.SpanIDAttr])
+	assert.Equal(t, map[string]any{"key": "value"}, lines[0]["group"])
+}
+
+func TestSetSlogDefault(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	err := logging.Initialize(zerolog.DebugLevel, &buf, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+
+	logging.SetSlogDefault()
+	slog.Warn("default message")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "warn", lines[0]["level"])
+	assert.Equal(t, "default message", lines[0]["message"])
+	assert.Equal(t, serviceName, lines[0]["service"])
+}
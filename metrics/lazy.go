@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LazyCollectors are collectors registered with [RegisterMetrics] the first time they are used, so a package can
+// declare its metrics before the metrics package is initialized, and record them from any goroutine. When the metrics
+// package is initialized again, or shut down, the collectors are created and registered again on their next use, so
+// they are exported by the new registry or server.
+type LazyCollectors struct {
+	create func() []prometheus.Collector
+
+	// mu serializes the creation of the collectors; it is not held by Get when the cached collectors are current.
+	mu     sync.Mutex
+	cached atomic.Pointer[lazyState]
+}
+
+// lazyState are the collectors registered for a generation of the metrics package, or nil ones when the package was
+// not initialized.
+type lazyState struct {
+	generation uint64
+	collectors []prometheus.Collector
+}
+
+// NewLazyCollectors returns the [LazyCollectors] created by create. create is called with [Namespace] set, and again
+// every time the collectors must be registered again.
+func NewLazyCollectors(create func() []prometheus.Collector) *LazyCollectors {
+	return &LazyCollectors{create: create}
+}
+
+// Get returns the collectors, creating and registering them if required. It returns nil when the metrics package
+// has not been initialized. It only takes a lock when the metrics package was initialized or shut down since the last
+// call, so it can be called for every request.
+func (l *LazyCollectors) Get() []prometheus.Collector {
+	if s := l.cached.Load(); s != nil && s.generation == generation.Load() {
+		return s.collectors
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	mu.Lock()
+	current, initialized := generation.Load(), registry != nil
+	mu.Unlock()
+	if s := l.cached.Load(); s != nil && s.generation == current {
+		return s.collectors
+	}
+	if !initialized {
+		l.cached.Store(&lazyState{generation: current})
+		return nil
+	}
+
+	collectors := l.create()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if generation.Load() != current {
+		// the metrics package was initialized or shut down while the collectors were created
+		return nil
+	}
+	registeredMetrics = append(registeredMetrics, collectors...)
+	registry.MustRegister(collectors...)
+	l.cached.Store(&lazyState{generation: current, collectors: collectors})
+	return collectors
+}
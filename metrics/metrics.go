@@ -10,22 +10,41 @@ import (
 	"github.com/rs/zerolog/log"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 const defaultPort = "9090"
 
+// metricsPath is the path the metrics are published on.
+const metricsPath = "/metrics"
+
 var (
-	apiName           string
-	mPort             string
+	apiName string
+	mPort   string
+	server  *http.Server
+	ctx     context.Context
+
+	// mu guards the registry and the collectors registered with it, which are registered from the goroutines that use
+	// them by [LazyCollectors].
+	mu                sync.Mutex
 	nspace            string
 	registry          *prometheus.Registry
-	server            *http.Server
-	ctx               context.Context
 	registeredMetrics []prometheus.Collector
+	// generation changes whenever the registered collectors are reset, by [InitializeWithPort] and
+	// [ShutdownWithContext], so [LazyCollectors] registers them again. It is only changed with mu held, but is read
+	// without it by [LazyCollectors.Get].
+	generation atomic.Uint64
+
+	handlersMu sync.Mutex
+	handlers   = make(map[string]http.Handler)
 )
 
 // Namespace returns the Namespace for the metrics of the API.
 func Namespace() string {
+	mu.Lock()
+	defer mu.Unlock()
 	return nspace
 }
 
@@ -41,6 +60,8 @@ func Port() string {
 
 // Registry returns the internal [prometheus.Registry] so it can be used directly if required.
 func Registry() *prometheus.Registry {
+	mu.Lock()
+	defer mu.Unlock()
 	return registry
 }
 
@@ -67,49 +88,93 @@ func InitializeWithPort(context context.Context, port string, namespace, service
 		return errors.New(fmt.Sprintf("invalid port value: `%s`; a valid port is a number between 1024 and 49151", port))
 	}
 
+	mu.Lock()
 	registry = prometheus.NewRegistry()
 	registeredMetrics = make([]prometheus.Collector, 0)
+	nspace = namespace
+	generation.Add(1)
+	mu.Unlock()
 
 	ctx = context
 	mPort = port
-	nspace = namespace
 	apiName = serviceName
 	return nil
 }
 
 // Publish exposes the metrics for scraping.
 func Publish() {
-	go func() {
-		gin.SetMode(gin.ReleaseMode)
-		router := gin.New()
-		router.Use(gin.Recovery())
-		promHandler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
-		router.GET("/metrics", gin.WrapH(promHandler))
-		server = &http.Server{
-			Addr:    fmt.Sprintf(":%s", mPort),
-			Handler: router.Handler(),
-		}
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+	promHandler := promhttp.HandlerFor(Registry(), promhttp.HandlerOpts{})
+	router.GET(metricsPath, gin.WrapH(promHandler))
+	handlersMu.Lock()
+	for path, h := range handlers {
+		router.Any(path, gin.WrapH(h))
+	}
+	handlersMu.Unlock()
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%s", mPort),
+		Handler: router.Handler(),
+	}
+	server = srv
 
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Panic().Err(err).Msg("metrics endpoint failed with error")
 		}
 	}()
 	log.Info().Msg("metrics endpoint started")
 }
 
+// Handle mounts handler on the metrics server at path, for every method, such as the logging.LevelHandler. It must be
+// called before [Publish]. Calling Handle again with the same path replaces the handler.
+func Handle(path string, handler http.Handler) error {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("invalid path: `%s`; it must start with /", path)
+	}
+	if path == metricsPath {
+		return fmt.Errorf("invalid path: `%s`; it is used by the metrics", path)
+	}
+	if handler == nil {
+		return errors.New("handler is required")
+	}
+
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers[path] = handler
+	return nil
+}
+
 // Shutdown ensures the server for the prom metrics is shutdown cleanly.
 func Shutdown() error {
-	if err := server.Shutdown(ctx); err != nil {
-		return err
+	return ShutdownWithContext(ctx)
+}
+
+// ShutdownWithContext shuts down the server for the prom metrics, waiting for active requests until shutdownCtx is
+// done. It is a no-op for the server if [Publish] has not been called.
+func ShutdownWithContext(shutdownCtx context.Context) error {
+	if server != nil {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		server = nil
 	}
+
+	mu.Lock()
+	defer mu.Unlock()
 	for _, metric := range registeredMetrics {
 		_ = registry.Unregister(metric)
 	}
+	registeredMetrics = registeredMetrics[:0]
+	generation.Add(1)
 	return nil
 }
 
 // RegisterMetrics is used to add one to or more metrics (collectors) to the registry.
 func RegisterMetrics(cMetrics ...prometheus.Collector) {
+	mu.Lock()
+	defer mu.Unlock()
 	registeredMetrics = append(registeredMetrics, cMetrics...)
 	registry.MustRegister(cMetrics...)
 }
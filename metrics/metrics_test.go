@@ -6,6 +6,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/twistingmercury/telemetry/v2/metrics"
+	"io"
+	"net/http"
+	"sync"
 	"testing"
 	"time"
 )
@@ -61,3 +64,74 @@ func TestPublish(t *testing.T) {
 	err = metrics.Shutdown()
 	assert.NoError(t, err)
 }
+
+func TestHandle(t *testing.T) {
+	assert.Error(t, metrics.Handle("loglevel", http.NotFoundHandler()))
+	assert.Error(t, metrics.Handle("/metrics", http.NotFoundHandler()))
+	assert.Error(t, metrics.Handle("/ping", nil))
+
+	require.NoError(t, metrics.Handle("/ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Method))
+	})))
+	require.NoError(t, metrics.InitializeWithPort(context.TODO(), "1025", "unit", "test"))
+	metrics.Publish()
+	defer func() { _ = metrics.ShutdownWithContext(context.Background()) }()
+
+	var body []byte
+	require.Eventually(t, func() bool {
+		req, _ := http.NewRequest(http.MethodPut, "http://localhost:1025/ping", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ = io.ReadAll(resp.Body)
+		return resp.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.MethodPut, string(body), "the handler should serve every method")
+}
+
+// gathered reports whether the registry of the metrics package exports the metric.
+func gathered(t *testing.T, name string) bool {
+	t.Helper()
+	families, err := metrics.Registry().Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLazyCollectors(t *testing.T) {
+	created := 0
+	lazy := metrics.NewLazyCollectors(func() []prometheus.Collector {
+		created++
+		ctr := prometheus.NewCounter(prometheus.CounterOpts{Namespace: metrics.Namespace(), Name: "lazy_total"})
+		return []prometheus.Collector{ctr}
+	})
+
+	require.NoError(t, metrics.InitializeWithPort(context.TODO(), "1026", "unit", "test"))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lazy.Get()[0].(prometheus.Counter).Inc()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, created, "the collectors should be created once")
+	assert.True(t, gathered(t, "unit_lazy_total"))
+
+	require.NoError(t, metrics.ShutdownWithContext(context.Background()))
+	assert.False(t, gathered(t, "unit_lazy_total"))
+	lazy.Get()
+	assert.True(t, gathered(t, "unit_lazy_total"), "the collectors should be registered again after a shutdown")
+
+	require.NoError(t, metrics.InitializeWithPort(context.TODO(), "1026", "other", "test"))
+	lazy.Get()
+	assert.True(t, gathered(t, "other_lazy_total"), "the collectors should be registered with the new registry")
+	assert.Equal(t, 3, created)
+}
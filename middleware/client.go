@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// transportErrorStatus is the status_code label used when a request fails without a response.
+const transportErrorStatus = "error"
+
+// urlTemplateKey is the url.template attribute, which the semantic conventions used by this module do not define.
+const urlTemplateKey = attribute.Key("url.template")
+
+// ClientOptions configures the HTTP client transport.
+type ClientOptions struct {
+	// Base is the transport that sends the requests. The default is [http.DefaultTransport].
+	Base http.RoundTripper
+	// Logger writes the log messages for failed requests. The default is [logging.Default].
+	Logger *logging.Logger
+	// RouteFunc returns the URL template of a request, such as `/users/{id}`, which is added to the span name and
+	// recorded as the url.template attribute. The span is named after the method only when it is nil or returns "".
+	RouteFunc func(r *http.Request) string
+}
+
+// NewHTTPClient returns an [http.Client] that uses the transport returned by [NewTransport].
+func NewHTTPClient(opts ClientOptions) *http.Client {
+	return &http.Client{Transport: NewTransport(opts)}
+}
+
+// NewTransport returns an [http.RoundTripper] that, for every request, starts a client span, injects the trace
+// context into the request headers, and records the request duration. Requests that fail, or receive a 5xx
+// response, are logged at the error level. The metrics are only recorded when the metrics package has been
+// initialized, which can happen after the transport is created.
+//
+// The span ends when the response headers are received; reading the response body is not included.
+func NewTransport(opts ClientOptions) http.RoundTripper {
+	base := opts.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, logger: opts.Logger, routeFunc: opts.RouteFunc}
+}
+
+// transport is the [http.RoundTripper] returned by [NewTransport].
+type transport struct {
+	base      http.RoundTripper
+	logger    *logging.Logger
+	routeFunc func(r *http.Request) string
+}
+
+// RoundTrip sends the request with the base transport and records its telemetry.
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	name := r.Method
+	fullURL := redactURL(r.URL)
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLFull(fullURL),
+		semconv.ServerAddress(r.URL.Hostname()),
+	}
+	if t.routeFunc != nil {
+		if route := t.routeFunc(r); route != "" {
+			name += " " + route
+			attrs = append(attrs, urlTemplateKey.String(route))
+		}
+	}
+
+	ctx, span := tracing.Start(r.Context(), name, oteltrace.SpanKindClient, attrs...)
+	defer span.End()
+
+	// a RoundTripper must not modify the request it was given, so the headers are injected into a copy
+	r = r.Clone(ctx)
+	tracing.InjectContext(ctx, tracing.NewHTTPHeaderCarrier(r.Header))
+
+	resp, err := t.base.RoundTrip(r)
+	elapsed := time.Since(start)
+
+	args := []logging.KeyValue{
+		{Key: string(semconv.HTTPRequestMethodKey), Value: r.Method},
+		{Key: string(semconv.URLFullKey), Value: fullURL},
+		{Key: "duration_ms", Value: float64(elapsed.Microseconds()) / 1000},
+	}
+
+	logger := t.logger
+	if logger == nil {
+		logger = logging.Default()
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		newHTTPClientMetrics().observe(r.Method, r.URL.Host, transportErrorStatus, elapsed.Seconds())
+		logger.Error(ctx, err, "http client request failed", args...)
+		return resp, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	newHTTPClientMetrics().observe(r.Method, r.URL.Host, strconv.Itoa(resp.StatusCode), elapsed.Seconds())
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		args = append(args, logging.KeyValue{Key: string(semconv.HTTPResponseStatusCodeKey), Value: resp.StatusCode})
+		logger.Error(ctx, errors.New(http.StatusText(resp.StatusCode)), "http client request failed", args...)
+	}
+	return resp, nil
+}
+
+// redactURL returns u without its query string and fragment, which may carry tokens or personal data, and with the
+// password of its user info redacted.
+func redactURL(u *url.URL) string {
+	c := *u
+	c.RawQuery = ""
+	c.ForceQuery = false
+	c.Fragment = ""
+	c.RawFragment = ""
+	return c.Redacted()
+}
@@ -0,0 +1,141 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/middleware"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTPClient(t *testing.T) {
+	tel := setup(t)
+
+	var received oteltrace.SpanContext
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = oteltrace.SpanContextFromContext(tracing.ExtractContext(context.Background(), tracing.NewHTTPHeaderCarrier(r.Header)))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	ctx, parent := tracing.Start(context.Background(), "parent", oteltrace.SpanKindInternal)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/items", nil)
+	require.NoError(t, err)
+
+	resp, err := middleware.NewHTTPClient(middleware.ClientOptions{}).Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	parent.End()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Empty(t, req.Header.Get("traceparent"), "the caller's request should not be modified")
+
+	var span oteltrace.SpanContext
+	for _, s := range tel.endedSpans(t) {
+		if s.SpanKind != oteltrace.SpanKindClient {
+			continue
+		}
+		span = s.SpanContext
+		assert.Equal(t, "GET", s.Name)
+		assert.Equal(t, parent.SpanContext().SpanID(), s.Parent.SpanID())
+		assert.Equal(t, int64(http.StatusAccepted), attributeValue(s.Attributes, "http.response.status_code").AsInt64())
+		assert.Equal(t, codes.Unset, s.Status.Code)
+	}
+	require.True(t, span.IsValid(), "a client span should be recorded")
+	assert.Equal(t, span.TraceID(), received.TraceID(), "the trace context should be injected")
+	assert.Equal(t, span.SpanID(), received.SpanID())
+
+	assert.Empty(t, tel.logLines(t), "successful requests should not be logged")
+
+	histogram := metric(t, namespace+"_http_client_request_duration_seconds",
+		map[string]string{"method": "GET", "host": req.URL.Host, "status_code": "202"})
+	require.NotNil(t, histogram)
+	assert.Equal(t, uint64(1), histogram.GetHistogram().GetSampleCount())
+}
+
+func TestHTTPClientURL(t *testing.T) {
+	tel := setup(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL + "/users/42?token=secret#section")
+	require.NoError(t, err)
+	u.User = url.UserPassword("user", "password")
+	opts := middleware.ClientOptions{RouteFunc: func(*http.Request) string { return "/users/{id}" }}
+	resp, err := middleware.NewHTTPClient(opts).Get(u.String())
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	expected := "http://user:xxxxx@" + u.Host + "/users/42"
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /users/{id}", spans[0].Name)
+	assert.Equal(t, "/users/{id}", attributeValue(spans[0].Attributes, "url.template").AsString())
+	assert.Equal(t, expected, attributeValue(spans[0].Attributes, "url.full").AsString())
+
+	lines := tel.logLines(t)
+	require.Len(t, lines, 1)
+	assert.Equal(t, expected, lines[0]["url.full"], "the query string should not be logged")
+}
+
+func TestHTTPClientServerError(t *testing.T) {
+	tel := setup(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	resp, err := middleware.NewHTTPClient(middleware.ClientOptions{}).Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+
+	lines := tel.logLines(t)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "error", lines[0]["level"])
+	assert.Equal(t, float64(http.StatusBadGateway), lines[0]["http.response.status_code"])
+	assert.Equal(t, spans[0].SpanContext.TraceID().String(), lines[0][logging.TraceIDAttr])
+}
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestHTTPClientTransportError(t *testing.T) {
+	tel := setup(t)
+
+	client := middleware.NewHTTPClient(middleware.ClientOptions{Base: failingTransport{}})
+	_, err := client.Get("http://unreachable.test/")
+	require.Error(t, err)
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Len(t, spans[0].Events, 1, "the error should be recorded on the span")
+
+	lines := tel.logLines(t)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "http client request failed", lines[0]["message"])
+	assert.Equal(t, "connection refused", lines[0]["error"])
+
+	histogram := metric(t, namespace+"_http_client_request_duration_seconds",
+		map[string]string{"host": "unreachable.test", "status_code": "error"})
+	require.NotNil(t, histogram)
+}
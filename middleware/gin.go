@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/twistingmercury/telemetry/v2/logging"
+)
+
+// unmatchedRoute is the route label of the requests that do not match a gin route. Their span is named after the
+// method only.
+const unmatchedRoute = "unmatched"
+
+// Gin returns gin middleware that, for every request, extracts the trace context from the request headers, starts
+// a server span named after the matched route ([gin.Context.FullPath]), logs the request, and records the request
+// count and duration. The span context is set on c.Request, so handlers can log with c.Request.Context().
+//
+// Panics in handlers are recovered, logged with their stack trace and answered with a 500 response.
+// [Options.RouteFunc], when set, replaces the matched route.
+func Gin(opts Options) gin.HandlerFunc {
+	m := newHTTPServerMetrics()
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if opts.RouteFunc != nil {
+			route = opts.RouteFunc(c.Request)
+		}
+
+		sr := startServerRequest(c.Request, route, unmatchedRoute)
+		c.Request = c.Request.WithContext(sr.ctx)
+
+		defer func() {
+			if v := recover(); v != nil {
+				c.AbortWithStatus(http.StatusInternalServerError)
+				sr.end(opts.Logger, m, http.StatusInternalServerError, fmt.Errorf("panic: %v", v),
+					logging.KeyValue{Key: "stack", Value: string(debug.Stack())})
+				return
+			}
+
+			var err error
+			status := c.Writer.Status()
+			if status >= http.StatusInternalServerError && len(c.Errors) > 0 {
+				err = c.Errors.Last().Err
+			}
+			sr.end(opts.Logger, m, status, err)
+		}()
+
+		c.Next()
+	}
+}
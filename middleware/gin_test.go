@@ -0,0 +1,146 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func newGinRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Gin(middleware.Options{}))
+	return router
+}
+
+func TestGin(t *testing.T) {
+	tel := setup(t)
+
+	router := newGinRouter()
+	router.GET("/users/:id", func(c *gin.Context) {
+		logging.Info(c.Request.Context(), "handling request")
+		c.String(http.StatusOK, c.Param("id"))
+	})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "42", rr.Body.String())
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /users/:id", span.Name)
+	assert.Equal(t, oteltrace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, "/users/:id", attributeValue(span.Attributes, "http.route").AsString())
+	assert.Equal(t, int64(http.StatusOK), attributeValue(span.Attributes, "http.response.status_code").AsInt64())
+
+	lines := tel.logLines(t)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "handling request", lines[0]["message"])
+	assert.Equal(t, span.SpanContext.TraceID().String(), lines[0][logging.TraceIDAttr],
+		"handlers should log with the span context on c.Request")
+	assert.Equal(t, span.SpanContext.SpanID().String(), lines[0][logging.SpanIDAttr])
+	assert.Equal(t, "/users/:id", lines[1]["http.route"])
+
+	counter := metric(t, namespace+"_http_server_requests_total",
+		map[string]string{"method": "GET", "route": "/users/:id", "status_code": "200"})
+	require.NotNil(t, counter)
+	assert.Equal(t, float64(1), counter.GetCounter().GetValue())
+}
+
+func TestGinUnmatchedRoute(t *testing.T) {
+	tel := setup(t)
+
+	router := newGinRouter()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/missing/42", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET", spans[0].Name)
+	assert.Equal(t, attribute.INVALID, attributeValue(spans[0].Attributes, "http.route").Type())
+
+	counter := metric(t, namespace+"_http_server_requests_total", map[string]string{"route": "unmatched", "status_code": "404"})
+	require.NotNil(t, counter)
+}
+
+func TestGinServerError(t *testing.T) {
+	tel := setup(t)
+
+	router := newGinRouter()
+	router.GET("/fail", func(c *gin.Context) {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.New("database unavailable"))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "database unavailable", spans[0].Status.Description)
+
+	lines := tel.logLines(t)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "error", lines[0]["level"])
+	assert.Equal(t, "database unavailable", lines[0]["error"])
+}
+
+func TestGinPanic(t *testing.T) {
+	tel := setup(t)
+
+	router := newGinRouter()
+	router.GET("/panic", func(c *gin.Context) {
+		panic("handler panic")
+	})
+
+	rr := httptest.NewRecorder()
+	assert.NotPanics(t, func() {
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	})
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+
+	lines := tel.logLines(t)
+	require.Len(t, lines, 1, "the panic should be logged once")
+	assert.Equal(t, "http request failed", lines[0]["message"])
+	assert.Equal(t, "panic: handler panic", lines[0]["error"])
+	assert.Contains(t, lines[0]["stack"], "gin_test.go")
+	assert.Equal(t, spans[0].SpanContext.TraceID().String(), lines[0][logging.TraceIDAttr])
+	assert.Equal(t, float64(http.StatusInternalServerError), lines[0]["http.response.status_code"])
+
+	counter := metric(t, namespace+"_http_server_requests_total", map[string]string{"route": "/panic", "status_code": "500"})
+	require.NotNil(t, counter)
+	assert.Equal(t, float64(1), counter.GetCounter().GetValue())
+}
+
+func TestGinWithRouteFunc(t *testing.T) {
+	tel := setup(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Gin(middleware.Options{RouteFunc: func(*http.Request) string { return "custom" }}))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(context.Background())
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET custom", spans[0].Name)
+}
@@ -0,0 +1,287 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCOptions configures the gRPC interceptors.
+type GRPCOptions struct {
+	// Logger writes the call log messages. The default is [logging.Default].
+	Logger *logging.Logger
+}
+
+// serverErrorCodes are the status codes that are recorded as errors by the server interceptors. The other codes are
+// the result of the caller's request, as with 4xx HTTP responses.
+var serverErrorCodes = map[codes.Code]bool{
+	codes.Unknown:          true,
+	codes.DeadlineExceeded: true,
+	codes.Unimplemented:    true,
+	codes.Internal:         true,
+	codes.Unavailable:      true,
+	codes.DataLoss:         true,
+}
+
+// UnaryServerInterceptor returns a [grpc.UnaryServerInterceptor] that, for every call, extracts the trace context from
+// the incoming metadata, starts a server span, logs the call, and records the call count and duration. The metrics
+// are only recorded when the metrics package has been initialized, which can happen after the interceptor is created.
+func UnaryServerInterceptor(opts GRPCOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		call := startServerCall(ctx, info.FullMethod)
+		resp, err := handler(call.ctx, req)
+		call.end(opts.Logger, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a [grpc.StreamServerInterceptor] that instruments streaming calls like
+// [UnaryServerInterceptor]. The span ends when the handler returns, and is on the context of the stream passed to it.
+func StreamServerInterceptor(opts GRPCOptions) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		call := startServerCall(ss.Context(), info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: call.ctx})
+		call.end(opts.Logger, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns a [grpc.UnaryClientInterceptor] that, for every call, starts a client span, injects
+// the trace context into the outgoing metadata, and records the call count and duration. Calls are logged at the
+// debug level, or at the error level when they fail.
+func UnaryClientInterceptor(opts GRPCOptions) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		call := startClientCall(ctx, method, cc)
+		err := invoker(call.ctx, method, req, reply, cc, callOpts...)
+		call.end(opts.Logger, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a [grpc.StreamClientInterceptor] that instruments streaming calls like
+// [UnaryClientInterceptor]. The span ends when the stream ends: when RecvMsg returns an error (io.EOF for a stream
+// that completed), when the response of a client-streaming call is received, or when the call's context is done.
+func StreamClientInterceptor(opts GRPCOptions) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		call := startClientCall(ctx, method, cc)
+		cs, err := streamer(call.ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			call.end(opts.Logger, err)
+			return nil, err
+		}
+
+		s := &clientStream{ClientStream: cs, desc: desc, done: make(chan struct{})}
+		s.finish = func(err error) {
+			s.once.Do(func() {
+				close(s.done)
+				call.end(opts.Logger, err)
+			})
+		}
+		go func() {
+			select {
+			case <-s.done:
+			case <-call.ctx.Done():
+				s.finish(status.FromContextError(call.ctx.Err()).Err())
+			}
+		}()
+		return s, nil
+	}
+}
+
+// grpcCall is the telemetry of a call handled or sent by one of the gRPC interceptors.
+type grpcCall struct {
+	ctx     context.Context
+	span    oteltrace.Span
+	start   time.Time
+	kind    oteltrace.SpanKind
+	service string
+	method  string
+}
+
+// startServerCall extracts the trace context from the incoming metadata of ctx and starts a server span for the call.
+func startServerCall(ctx context.Context, fullMethod string) *grpcCall {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = tracing.ExtractContext(ctx, metadataCarrier(md))
+	return startCall(ctx, fullMethod, oteltrace.SpanKindServer)
+}
+
+// startClientCall starts a client span for the call and injects the trace context into the outgoing metadata.
+func startClientCall(ctx context.Context, fullMethod string, cc *grpc.ClientConn) *grpcCall {
+	var attribs []attribute.KeyValue
+	if cc != nil {
+		attribs = append(attribs, semconv.ServerAddress(cc.Target()))
+	}
+	call := startCall(ctx, fullMethod, oteltrace.SpanKindClient, attribs...)
+
+	md, ok := metadata.FromOutgoingContext(call.ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	tracing.InjectContext(call.ctx, metadataCarrier(md))
+	call.ctx = metadata.NewOutgoingContext(call.ctx, md)
+	return call
+}
+
+func startCall(ctx context.Context, fullMethod string, kind oteltrace.SpanKind, attribs ...attribute.KeyValue) *grpcCall {
+	service, method := splitMethod(fullMethod)
+	attribs = append(attribs,
+		semconv.RPCSystemGRPC,
+		semconv.RPCService(service),
+		semconv.RPCMethod(method),
+	)
+	ctx, span := tracing.Start(ctx, strings.TrimPrefix(fullMethod, "/"), kind, attribs...)
+
+	return &grpcCall{ctx: ctx, span: span, start: time.Now(), kind: kind, service: service, method: method}
+}
+
+// end ends the span, logs the call and records its metrics. Server calls that end with one of the
+// serverErrorCodes, and client calls that end with any status code other than OK, are recorded as errors on the span
+// and logged at the error level.
+func (c *grpcCall) end(logger *logging.Logger, err error) {
+	elapsed := time.Since(c.start)
+	code := status.Code(err)
+
+	failed := code != codes.OK
+	if c.kind == oteltrace.SpanKindServer {
+		failed = serverErrorCodes[code]
+	}
+
+	c.span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if failed {
+		c.span.RecordError(err)
+		c.span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+	c.span.End()
+
+	side := "server"
+	if c.kind == oteltrace.SpanKindClient {
+		side = "client"
+	}
+	newGRPCMetrics(side).observe(c.service, c.method, code.String(), elapsed.Seconds())
+
+	if logger == nil {
+		logger = logging.Default()
+	}
+	args := []logging.KeyValue{
+		{Key: string(semconv.RPCServiceKey), Value: c.service},
+		{Key: string(semconv.RPCMethodKey), Value: c.method},
+		{Key: string(semconv.RPCGRPCStatusCodeKey), Value: int(code)},
+		{Key: "duration_ms", Value: float64(elapsed.Microseconds()) / 1000},
+	}
+
+	msg := "grpc request"
+	if c.kind == oteltrace.SpanKindClient {
+		msg = "grpc client request"
+	}
+	switch {
+	case failed:
+		logger.Error(c.ctx, err, msg+" failed", args...)
+	case c.kind == oteltrace.SpanKindClient:
+		logger.Debug(c.ctx, msg, args...)
+	default:
+		logger.Info(c.ctx, msg, args...)
+	}
+}
+
+// splitMethod splits a full method name, such as `/package.Service/Method`, into its service and method.
+func splitMethod(fullMethod string) (service, method string) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// metadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+// Get returns the first value for key.
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Set sets the value for key, replacing any existing values.
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys returns the metadata keys.
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// serverStream overrides the context of the wrapped [grpc.ServerStream] with the context holding the span.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context holding the server span.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// clientStream ends the call's telemetry when the wrapped [grpc.ClientStream] ends.
+type clientStream struct {
+	grpc.ClientStream
+	desc   *grpc.StreamDesc
+	once   sync.Once
+	done   chan struct{}
+	finish func(err error)
+}
+
+// Header returns the header metadata, ending the call if it failed.
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.finish(err)
+	}
+	return md, err
+}
+
+// SendMsg sends m, ending the call if it failed. io.EOF is not a failure: the status of the call is returned by
+// RecvMsg.
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && err != io.EOF {
+		s.finish(err)
+	}
+	return err
+}
+
+// RecvMsg receives a message into m, ending the call when the stream is complete.
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.desc.ServerStreams:
+		// the single response of a unary or client-streaming call ends it
+		s.finish(nil)
+	}
+	return err
+}
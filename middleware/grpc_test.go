@@ -0,0 +1,174 @@
+package middleware_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/middleware"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const healthService = "grpc.health.v1.Health"
+
+// healthServer fails health checks of the "broken" service with an internal error.
+type healthServer struct {
+	*health.Server
+}
+
+func (s healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if req.GetService() == "broken" {
+		return nil, status.Error(codes.Internal, "broken")
+	}
+	return s.Server.Check(ctx, req)
+}
+
+// dialHealth starts an in-process health server with the server interceptors, and returns a client connected to it
+// with the client interceptors, and the server.
+func dialHealth(t *testing.T) (healthpb.HealthClient, *grpc.Server) {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(middleware.UnaryServerInterceptor(middleware.GRPCOptions{})),
+		grpc.StreamInterceptor(middleware.StreamServerInterceptor(middleware.GRPCOptions{})),
+	)
+	healthpb.RegisterHealthServer(srv, healthServer{health.NewServer()})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.GracefulStop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(middleware.UnaryClientInterceptor(middleware.GRPCOptions{})),
+		grpc.WithStreamInterceptor(middleware.StreamClientInterceptor(middleware.GRPCOptions{})),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn), srv
+}
+
+// spansByKind returns the first server and client spans.
+func spansByKind(spans tracetest.SpanStubs) (server, client tracetest.SpanStub) {
+	for _, s := range spans {
+		switch s.SpanKind {
+		case oteltrace.SpanKindServer:
+			server = s
+		case oteltrace.SpanKindClient:
+			client = s
+		}
+	}
+	return server, client
+}
+
+func TestGRPCUnary(t *testing.T) {
+	tel := setup(t)
+	client, _ := dialHealth(t)
+
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+	server, clientSpan := spansByKind(tel.endedSpans(t))
+	assert.Equal(t, healthService+"/Check", server.Name)
+	assert.Equal(t, healthService+"/Check", clientSpan.Name)
+	assert.Equal(t, clientSpan.SpanContext.TraceID(), server.SpanContext.TraceID(), "the trace context should be propagated")
+	assert.Equal(t, clientSpan.SpanContext.SpanID(), server.Parent.SpanID())
+	assert.Equal(t, "grpc", attributeValue(server.Attributes, "rpc.system").AsString())
+	assert.Equal(t, healthService, attributeValue(server.Attributes, "rpc.service").AsString())
+	assert.Equal(t, "Check", attributeValue(server.Attributes, "rpc.method").AsString())
+	assert.Equal(t, int64(codes.OK), attributeValue(server.Attributes, "rpc.grpc.status_code").AsInt64())
+	assert.Equal(t, otelcodes.Unset, server.Status.Code)
+
+	lines := tel.logLines(t)
+	require.Len(t, lines, 2)
+	messages := []any{lines[0]["message"], lines[1]["message"]}
+	assert.ElementsMatch(t, []any{"grpc request", "grpc client request"}, messages)
+	for _, line := range lines {
+		assert.Equal(t, server.SpanContext.TraceID().String(), line[logging.TraceIDAttr])
+	}
+
+	for _, side := range []string{"server", "client"} {
+		labels := map[string]string{"service": healthService, "method": "Check", "status_code": "OK"}
+		counter := metric(t, namespace+"_grpc_"+side+"_requests_total", labels)
+		require.NotNil(t, counter, side)
+		assert.Equal(t, float64(1), counter.GetCounter().GetValue())
+		histogram := metric(t, namespace+"_grpc_"+side+"_request_duration_seconds", labels)
+		require.NotNil(t, histogram, side)
+		assert.Equal(t, uint64(1), histogram.GetHistogram().GetSampleCount())
+	}
+}
+
+func TestGRPCUnaryErrors(t *testing.T) {
+	tests := []struct {
+		service     string
+		code        codes.Code
+		serverError bool
+	}{
+		{"broken", codes.Internal, true},
+		{"missing", codes.NotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			tel := setup(t)
+			client, _ := dialHealth(t)
+
+			_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: tt.service})
+			assert.Equal(t, tt.code, status.Code(err))
+
+			server, clientSpan := spansByKind(tel.endedSpans(t))
+			assert.Equal(t, otelcodes.Error, clientSpan.Status.Code, "failed calls are errors for the client")
+			if tt.serverError {
+				assert.Equal(t, otelcodes.Error, server.Status.Code)
+			} else {
+				assert.Equal(t, otelcodes.Unset, server.Status.Code)
+			}
+
+			counter := metric(t, namespace+"_grpc_server_requests_total", map[string]string{"status_code": tt.code.String()})
+			require.NotNil(t, counter)
+			assert.Equal(t, float64(1), counter.GetCounter().GetValue())
+		})
+	}
+}
+
+func TestGRPCStream(t *testing.T) {
+	tel := setup(t)
+	client, srv := dialHealth(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	require.Eventually(t, func() bool {
+		return metric(t, namespace+"_grpc_server_requests_total", map[string]string{"method": "Watch"}) != nil
+	}, time.Second, 10*time.Millisecond, "the server call should end when the stream is canceled")
+	srv.GracefulStop()
+
+	server, clientSpan := spansByKind(tel.endedSpans(t))
+	assert.Equal(t, healthService+"/Watch", server.Name)
+	assert.Equal(t, healthService+"/Watch", clientSpan.Name)
+	assert.Equal(t, clientSpan.SpanContext.SpanID(), server.Parent.SpanID(), "the trace context should be propagated")
+	assert.Equal(t, int64(codes.Canceled), attributeValue(clientSpan.Attributes, "rpc.grpc.status_code").AsInt64())
+}
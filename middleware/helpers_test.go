@@ -0,0 +1,123 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/metrics"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	serviceName    = "test-service"
+	serviceVersion = "1.0.0"
+	environment    = "unit-test"
+	namespace      = "unit"
+)
+
+// telemetry holds the in-memory sinks the three signals are initialized against.
+type telemetry struct {
+	spans *tracetest.InMemoryExporter
+	logs  *syncBuffer
+}
+
+// syncBuffer is a bytes.Buffer that the server and client goroutines can log to while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func setup(t *testing.T) *telemetry {
+	t.Helper()
+
+	tel := &telemetry{spans: tracetest.NewInMemoryExporter(), logs: &syncBuffer{}}
+	require.NoError(t, tracing.InitializeWithOptions(tracing.Options{Exporter: tel.spans, ServiceName: serviceName}))
+	t.Cleanup(func() { _ = tracing.Shutdown(context.Background()) })
+
+	require.NoError(t, logging.Initialize(zerolog.DebugLevel, tel.logs, serviceName, serviceVersion, environment))
+	require.NoError(t, metrics.InitializeWithPort(context.Background(), "9091", namespace, serviceName))
+	return tel
+}
+
+// endedSpans flushes and returns the spans ended so far.
+func (tel *telemetry) endedSpans(t *testing.T) tracetest.SpanStubs {
+	t.Helper()
+	require.NoError(t, tracing.ForceFlush(context.Background()))
+	return tel.spans.GetSpans()
+}
+
+// logLines returns the decoded JSON log lines written so far.
+func (tel *telemetry) logLines(t *testing.T) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(tel.logs.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m), line)
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+// metric returns the metric with the given name and labels from the metrics registry, or nil if there is none.
+func metric(t *testing.T, name string, labels map[string]string) *dto.Metric {
+	t.Helper()
+
+	families, err := metrics.Registry().Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if hasLabels(m, labels) {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+func hasLabels(m *dto.Metric, labels map[string]string) bool {
+	matched := 0
+	for _, lp := range m.GetLabel() {
+		if v, ok := labels[lp.GetName()]; ok && v == lp.GetValue() {
+			matched++
+		}
+	}
+	return matched == len(labels)
+}
+
+func attributeValue(attrs []attribute.KeyValue, key attribute.Key) attribute.Value {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return attribute.Value{}
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// unknownRoute is the route label of the requests whose route template is not known.
+const unknownRoute = "unknown"
+
+// Options configures the server middleware.
+type Options struct {
+	// RouteFunc returns the route template of a request, such as `/users/{id}`, which is used in the span name, as the
+	// `http.route` attribute and as the route metric label. By default, [HTTP] does not know the route: the span is
+	// named after the method only and the route label is `unknown`, so the paths of the requests cannot create an
+	// unbounded number of metric series. RouteFunc must return a template, not the path of the request.
+	RouteFunc func(r *http.Request) string
+	// Logger writes the request log messages. The default is [logging.Default].
+	Logger *logging.Logger
+}
+
+// HTTP returns net/http middleware that, for every request, extracts the trace context from the request headers,
+// starts a server span, logs the request, and records the request count and duration. The metrics are only
+// recorded when the metrics package has been initialized, which can happen after the middleware is created.
+//
+// A handler that panics with [http.ErrAbortHandler] to abort the response is not recorded as an error.
+func HTTP(opts Options) func(http.Handler) http.Handler {
+	m := newHTTPServerMetrics()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var route string
+			if opts.RouteFunc != nil {
+				route = opts.RouteFunc(r)
+			}
+
+			sr := startServerRequest(r, route, unknownRoute)
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			defer func() {
+				if v := recover(); v != nil {
+					if v == http.ErrAbortHandler {
+						sr.span.End()
+						panic(v)
+					}
+					sr.end(opts.Logger, m, http.StatusInternalServerError, fmt.Errorf("panic: %v", v))
+					panic(v)
+				}
+				sr.end(opts.Logger, m, rec.status, nil)
+			}()
+
+			next.ServeHTTP(rec, r.WithContext(sr.ctx))
+		})
+	}
+}
+
+// serverRequest is the telemetry of a request handled by one of the server middlewares.
+type serverRequest struct {
+	ctx    context.Context
+	span   oteltrace.Span
+	start  time.Time
+	method string
+	// route is the route label of the metrics and of the log message.
+	route string
+}
+
+// startServerRequest extracts the trace context from the headers of r and starts a server span for it. route is the
+// route template of r, or empty when it is not known, in which case the span is named after the method only, has no
+// `http.route` attribute, and fallback is the route label of the metrics and of the log message.
+func startServerRequest(r *http.Request, route, fallback string) *serverRequest {
+	name := r.Method
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLPath(r.URL.Path),
+		semconv.URLScheme(scheme(r)),
+		semconv.UserAgentOriginal(r.UserAgent()),
+	}
+	if route != "" {
+		name += " " + route
+		attrs = append(attrs, semconv.HTTPRoute(route))
+	} else {
+		route = fallback
+	}
+
+	ctx := tracing.ExtractContext(r.Context(), tracing.NewHTTPHeaderCarrier(r.Header))
+	ctx, span := tracing.Start(ctx, name, oteltrace.SpanKindServer, attrs...)
+
+	return &serverRequest{ctx: ctx, span: span, start: time.Now(), method: r.Method, route: route}
+}
+
+// end ends the span, logs the request and records its metrics. Responses with a 5xx status code are recorded as
+// errors on the span and logged at the error level. fields are added to the log entry.
+func (sr *serverRequest) end(
+	logger *logging.Logger, m *httpServerMetrics, status int, err error, fields ...logging.KeyValue,
+) {
+	elapsed := time.Since(sr.start)
+	statusCode := strconv.Itoa(status)
+
+	sr.span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		if err == nil {
+			err = errors.New(http.StatusText(status))
+		}
+		sr.span.RecordError(err)
+		sr.span.SetStatus(codes.Error, err.Error())
+	}
+	sr.span.End()
+
+	m.observe(sr.method, sr.route, statusCode, elapsed.Seconds())
+
+	if logger == nil {
+		logger = logging.Default()
+	}
+	args := []logging.KeyValue{
+		{Key: string(semconv.HTTPRequestMethodKey), Value: sr.method},
+		{Key: string(semconv.HTTPRouteKey), Value: sr.route},
+		{Key: string(semconv.HTTPResponseStatusCodeKey), Value: status},
+		{Key: "duration_ms", Value: float64(elapsed.Microseconds()) / 1000},
+	}
+	args = append(args, fields...)
+	if err != nil {
+		logger.Error(sr.ctx, err, "http request failed", args...)
+		return
+	}
+	logger.Info(sr.ctx, "http request", args...)
+}
+
+// scheme returns the URL scheme of the request.
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// statusRecorder records the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader records the status code and writes it to the wrapped [http.ResponseWriter].
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes b to the wrapped [http.ResponseWriter].
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush flushes the wrapped [http.ResponseWriter] if it supports it.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		r.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection of the wrapped [http.ResponseWriter], such as for a websocket upgrade, and records
+// the 101 Switching Protocols status. It returns [http.ErrNotSupported] when the wrapped writer cannot be hijacked.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && !r.wroteHeader {
+		r.status = http.StatusSwitchingProtocols
+		r.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped [http.ResponseWriter], so it can be used with [http.ResponseController].
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,213 @@
+package middleware_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/middleware"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTP(t *testing.T) {
+	tel := setup(t)
+
+	var handlerSpan oteltrace.SpanContext
+	handler := middleware.HTTP(middleware.Options{
+		RouteFunc: func(*http.Request) string { return "/users/{id}" },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = oteltrace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	parent := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10},
+		SpanID:     oteltrace.SpanID{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18},
+		TraceFlags: oteltrace.FlagsSampled,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/users/42", nil)
+	tracing.InjectContext(oteltrace.ContextWithSpanContext(context.Background(), parent), tracing.NewHTTPHeaderCarrier(req.Header))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "POST /users/{id}", span.Name)
+	assert.Equal(t, oteltrace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, parent.TraceID(), span.SpanContext.TraceID(), "the trace context should be extracted from the request")
+	assert.Equal(t, parent.SpanID(), span.Parent.SpanID())
+	assert.Equal(t, span.SpanContext.SpanID(), handlerSpan.SpanID(), "the span should be on the request context")
+	assert.Equal(t, "POST", attributeValue(span.Attributes, "http.request.method").AsString())
+	assert.Equal(t, "/users/{id}", attributeValue(span.Attributes, "http.route").AsString())
+	assert.Equal(t, int64(http.StatusCreated), attributeValue(span.Attributes, "http.response.status_code").AsInt64())
+	assert.Equal(t, codes.Unset, span.Status.Code)
+
+	lines := tel.logLines(t)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "info", lines[0]["level"])
+	assert.Equal(t, "POST", lines[0]["http.request.method"])
+	assert.Equal(t, "/users/{id}", lines[0]["http.route"])
+	assert.Equal(t, float64(http.StatusCreated), lines[0]["http.response.status_code"])
+	assert.Equal(t, parent.TraceID().String(), lines[0][logging.TraceIDAttr])
+
+	labels := map[string]string{"method": "POST", "route": "/users/{id}", "status_code": "201"}
+	counter := metric(t, namespace+"_http_server_requests_total", labels)
+	require.NotNil(t, counter)
+	assert.Equal(t, float64(1), counter.GetCounter().GetValue())
+	histogram := metric(t, namespace+"_http_server_request_duration_seconds", labels)
+	require.NotNil(t, histogram)
+	assert.Equal(t, uint64(1), histogram.GetHistogram().GetSampleCount())
+}
+
+func TestHTTPServerError(t *testing.T) {
+	tel := setup(t)
+
+	handler := middleware.HTTP(middleware.Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusServiceUnavailable)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET", spans[0].Name, "the span should be named after the method when the route is not known")
+	assert.Equal(t, attribute.INVALID, attributeValue(spans[0].Attributes, "http.route").Type(), "the path is not a route")
+	assert.Equal(t, "/fail", attributeValue(spans[0].Attributes, "url.path").AsString())
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Len(t, spans[0].Events, 1, "the error should be recorded on the span")
+
+	lines := tel.logLines(t)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "error", lines[0]["level"])
+	assert.Equal(t, http.StatusText(http.StatusServiceUnavailable), lines[0]["error"])
+
+	counter := metric(t, namespace+"_http_server_requests_total", map[string]string{"route": "unknown", "status_code": "503"})
+	require.NotNil(t, counter)
+	assert.Equal(t, float64(1), counter.GetCounter().GetValue())
+}
+
+func TestHTTPClientErrorIsNotAnError(t *testing.T) {
+	tel := setup(t)
+
+	handler := middleware.HTTP(middleware.Options{})(http.NotFoundHandler())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+
+	lines := tel.logLines(t)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "info", lines[0]["level"])
+	assert.Equal(t, float64(http.StatusNotFound), lines[0]["http.response.status_code"])
+}
+
+func TestHTTPPanic(t *testing.T) {
+	tel := setup(t)
+
+	handler := middleware.HTTP(middleware.Options{})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("handler panic")
+	}))
+
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	}, "the panic should be re-raised")
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, int64(http.StatusInternalServerError), attributeValue(spans[0].Attributes, "http.response.status_code").AsInt64())
+
+	lines := tel.logLines(t)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "panic: handler panic", lines[0]["error"])
+}
+
+func TestHTTPAbortHandler(t *testing.T) {
+	tel := setup(t)
+
+	handler := middleware.HTTP(middleware.Options{})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	}, "http.ErrAbortHandler should be re-raised")
+
+	spans := tel.endedSpans(t)
+	require.Len(t, spans, 1, "the span should be ended")
+	assert.NotEqual(t, codes.Error, spans[0].Status.Code)
+	assert.Empty(t, tel.logLines(t), "an aborted request should not be logged")
+	assert.Nil(t, metric(t, namespace+"_http_server_requests_total", map[string]string{"status_code": "500"}))
+}
+
+func TestHTTPHijack(t *testing.T) {
+	tel := setup(t)
+
+	handler := middleware.HTTP(middleware.Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if !assert.NoError(t, err, "the middleware should not hide http.Hijacker") {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		_ = rw.Flush()
+	}))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	require.Eventually(t, func() bool { return len(tel.endedSpans(t)) == 1 }, time.Second, 10*time.Millisecond)
+	span := tel.endedSpans(t)[0]
+	assert.Equal(t, int64(http.StatusSwitchingProtocols), attributeValue(span.Attributes, "http.response.status_code").AsInt64())
+	assert.NotNil(t, metric(t, namespace+"_http_server_requests_total", map[string]string{"status_code": "101"}))
+}
+
+func TestHTTPSharesMetrics(t *testing.T) {
+	tel := setup(t)
+
+	handler := middleware.HTTP(middleware.Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	handler2 := middleware.HTTP(middleware.Options{})(http.NotFoundHandler())
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", nil))
+	handler2.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/b", nil))
+
+	assert.Len(t, tel.endedSpans(t), 2)
+	counter := metric(t, namespace+"_http_server_requests_total", map[string]string{"route": "unknown", "status_code": "200"})
+	require.NotNil(t, counter, "middleware created more than once should share the registered metrics")
+}
+
+func TestHTTPMetricsInitializedLater(t *testing.T) {
+	handler := middleware.HTTP(middleware.Options{})(http.NotFoundHandler())
+	setup(t)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	counter := metric(t, namespace+"_http_server_requests_total", map[string]string{"status_code": "404"})
+	require.NotNil(t, counter, "the metrics initialized after the middleware was created should be recorded")
+	assert.Equal(t, float64(1), counter.GetCounter().GetValue())
+}
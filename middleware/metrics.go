@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/twistingmercury/telemetry/v2/metrics"
+)
+
+// httpServerMetrics records the RED metrics of the requests handled by the HTTP server middleware. The collectors are
+// looked up for every request, so the metrics package can be initialized after the middleware is created.
+type httpServerMetrics struct {
+	collectors *metrics.LazyCollectors
+}
+
+// httpServerCollectors are shared by all the HTTP and gin server middlewares.
+var httpServerCollectors = metrics.NewLazyCollectors(func() []prometheus.Collector {
+	labels := []string{"method", "route", "status_code"}
+	return []prometheus.Collector{
+		prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metrics.Namespace(),
+			Name:      "http_server_requests_total",
+			Help:      "The total count of HTTP requests handled by the server",
+		}, labels),
+		prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metrics.Namespace(),
+			Name:      "http_server_request_duration_seconds",
+			Help:      "Duration of the HTTP requests handled by the server",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+	}
+})
+
+// newHTTPServerMetrics returns the HTTP server metrics, shared by all the server middlewares.
+func newHTTPServerMetrics() *httpServerMetrics {
+	return &httpServerMetrics{collectors: httpServerCollectors}
+}
+
+// observe records a request, registering the metrics if required. It is a no-op when the metrics package has not
+// been initialized.
+func (m *httpServerMetrics) observe(method, route, statusCode string, seconds float64) {
+	c := m.collectors.Get()
+	if c == nil {
+		return
+	}
+	c[0].(*prometheus.CounterVec).WithLabelValues(method, route, statusCode).Inc()
+	c[1].(*prometheus.HistogramVec).WithLabelValues(method, route, statusCode).Observe(seconds)
+}
+
+// httpClientMetrics are the metrics recorded for the requests sent by the HTTP client transport.
+type httpClientMetrics struct {
+	duration *prometheus.HistogramVec
+}
+
+// httpClientCollectors are shared by all the HTTP client transports.
+var httpClientCollectors = metrics.NewLazyCollectors(func() []prometheus.Collector {
+	return []prometheus.Collector{
+		prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metrics.Namespace(),
+			Name:      "http_client_request_duration_seconds",
+			Help:      "Duration of the HTTP requests sent by the client",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "host", "status_code"}),
+	}
+})
+
+// newHTTPClientMetrics returns the HTTP client metrics, registering them if required. It returns nil when the
+// metrics package has not been initialized.
+func newHTTPClientMetrics() *httpClientMetrics {
+	c := httpClientCollectors.Get()
+	if c == nil {
+		return nil
+	}
+	return &httpClientMetrics{duration: c[0].(*prometheus.HistogramVec)}
+}
+
+// observe records a request. It is a no-op when m is nil.
+func (m *httpClientMetrics) observe(method, host, statusCode string, seconds float64) {
+	if m == nil {
+		return
+	}
+	m.duration.WithLabelValues(method, host, statusCode).Observe(seconds)
+}
+
+// grpcMetrics are the RED metrics recorded for the calls handled or sent by the gRPC interceptors.
+type grpcMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// grpcCollectors are the gRPC collectors of each side, shared by all the interceptors of the side.
+var grpcCollectors = map[string]*metrics.LazyCollectors{
+	"server": newGRPCCollectors("server"),
+	"client": newGRPCCollectors("client"),
+}
+
+// newGRPCCollectors returns the collectors of the gRPC metrics for side, which is either "server" or "client".
+func newGRPCCollectors(side string) *metrics.LazyCollectors {
+	return metrics.NewLazyCollectors(func() []prometheus.Collector {
+		labels := []string{"service", "method", "status_code"}
+		return []prometheus.Collector{
+			prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: metrics.Namespace(),
+				Name:      "grpc_" + side + "_requests_total",
+				Help:      "The total count of gRPC calls handled by the " + side,
+			}, labels),
+			prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace: metrics.Namespace(),
+				Name:      "grpc_" + side + "_request_duration_seconds",
+				Help:      "Duration of the gRPC calls handled by the " + side,
+				Buckets:   prometheus.DefBuckets,
+			}, labels),
+		}
+	})
+}
+
+// newGRPCMetrics returns the gRPC metrics for side, which is either "server" or "client", registering them if
+// required. It returns nil when the metrics package has not been initialized.
+func newGRPCMetrics(side string) *grpcMetrics {
+	c := grpcCollectors[side].Get()
+	if c == nil {
+		return nil
+	}
+	return &grpcMetrics{requests: c[0].(*prometheus.CounterVec), duration: c[1].(*prometheus.HistogramVec)}
+}
+
+// observe records a call. It is a no-op when m is nil.
+func (m *grpcMetrics) observe(service, method, statusCode string, seconds float64) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(service, method, statusCode).Inc()
+	m.duration.WithLabelValues(service, method, statusCode).Observe(seconds)
+}
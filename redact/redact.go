@@ -0,0 +1,324 @@
+// Package redact removes sensitive values, such as passwords, tokens and emails, from log fields and span attributes
+// before they are written or exported.
+//
+// A [Policy] is created once with [New] and passed to logging.Options.Redaction and tracing.Options.Redaction (or
+// telemetry.Config.Redaction), so the same fields are redacted in the logs and in the traces:
+//
+//	policy, err := redact.New(redact.Options{
+//		Keys:     []string{"password", "authorization", "api_key"},
+//		Matchers: []redact.Matcher{redact.JWT(redact.ActionMask), redact.Email(redact.ActionHash)},
+//	})
+//
+// A value is redacted when its key is deny-listed, or when a part of it matches a [Matcher]. The key action applies
+// to the whole value, whatever its type; matchers only apply to strings, string slices and errors.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Action is what is done to a sensitive value.
+type Action string
+
+const (
+	// ActionMask replaces the value, or the part of it that matched, with [Mask].
+	ActionMask Action = "mask"
+	// ActionHash replaces the value, or the part of it that matched, with a hash of it, so equal values can still be
+	// correlated without being revealed.
+	ActionHash Action = "hash"
+	// ActionDrop removes the field or attribute.
+	ActionDrop Action = "drop"
+)
+
+// Mask is the value written in place of a masked value.
+const Mask = "[REDACTED]"
+
+// hashPrefix starts every hashed value, so it is not mistaken for the original value.
+const hashPrefix = "sha256:"
+
+// hashLength is the number of hex characters of the hash kept in a hashed value.
+const hashLength = 16
+
+// Matcher finds sensitive parts of string values, whatever their key.
+type Matcher struct {
+	// Pattern matches the sensitive parts of a value. It is required.
+	Pattern *regexp.Regexp
+	// Validate, when set, is called with every part matched by Pattern, and only the parts it returns true for are
+	// sensitive, such as the card numbers with a valid check digit.
+	Validate func(string) bool
+	// Action is done to the matched parts. ActionDrop removes the whole field. The default is ActionMask.
+	Action Action
+}
+
+var (
+	jwtPattern   = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	cardPattern  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// JWT returns a [Matcher] of JSON Web Tokens, such as the bearer token of an `Authorization` header.
+func JWT(action Action) Matcher {
+	return Matcher{Pattern: jwtPattern, Action: action}
+}
+
+// CardNumber returns a [Matcher] of payment card numbers of 13 to 19 digits, optionally grouped with spaces or
+// dashes, that have a valid Luhn check digit.
+func CardNumber(action Action) Matcher {
+	return Matcher{Pattern: cardPattern, Validate: luhn, Action: action}
+}
+
+// Email returns a [Matcher] of email addresses.
+func Email(action Action) Matcher {
+	return Matcher{Pattern: emailPattern, Action: action}
+}
+
+// Options are the settings used by [New] to create a [Policy].
+type Options struct {
+	// Keys are the deny-listed field and attribute keys, such as `password`. They are matched ignoring case, against
+	// the whole key or its last dot-separated segment, so `password` also matches `user.password`.
+	Keys []string
+	// KeyAction is done to the values of the deny-listed keys. The default is ActionMask.
+	KeyAction Action
+	// Matchers find the sensitive parts of the values of any key.
+	Matchers []Matcher
+	// HashKey, when set, is the HMAC-SHA256 key of ActionHash, so the hashes cannot be reversed by hashing guesses
+	// without it. Without it, values are hashed with SHA-256.
+	HashKey []byte
+}
+
+// Policy redacts the sensitive values of log fields and span attributes. A nil *Policy redacts nothing. A Policy is
+// safe for concurrent use.
+type Policy struct {
+	keys      map[string]bool
+	keyAction Action
+	matchers  []Matcher
+	hashKey   []byte
+}
+
+// New creates a [Policy] configured by opts.
+func New(opts Options) (*Policy, error) {
+	p := &Policy{
+		keys:      make(map[string]bool, len(opts.Keys)),
+		keyAction: opts.KeyAction,
+		matchers:  make([]Matcher, 0, len(opts.Matchers)),
+		hashKey:   opts.HashKey,
+	}
+
+	for _, k := range opts.Keys {
+		if k = strings.TrimSpace(k); k != "" {
+			p.keys[strings.ToLower(k)] = true
+		}
+	}
+
+	if p.keyAction == "" {
+		p.keyAction = ActionMask
+	}
+	if err := validateAction(p.keyAction); err != nil {
+		return nil, err
+	}
+
+	for _, m := range opts.Matchers {
+		if m.Pattern == nil {
+			return nil, errors.New("matcher pattern is required")
+		}
+		if m.Action == "" {
+			m.Action = ActionMask
+		}
+		if err := validateAction(m.Action); err != nil {
+			return nil, err
+		}
+		p.matchers = append(p.matchers, m)
+	}
+	return p, nil
+}
+
+func validateAction(a Action) error {
+	switch a {
+	case ActionMask, ActionHash, ActionDrop:
+		return nil
+	default:
+		return fmt.Errorf("invalid redaction action: `%s`", a)
+	}
+}
+
+// Value returns the value to write for key, and false when the field must be dropped.
+func (p *Policy) Value(key string, value any) (any, bool) {
+	if p == nil {
+		return value, true
+	}
+
+	if p.deniedKey(key) {
+		switch p.keyAction {
+		case ActionDrop:
+			return nil, false
+		case ActionHash:
+			return p.hash(fmt.Sprint(value)), true
+		default:
+			return Mask, true
+		}
+	}
+
+	if len(p.matchers) == 0 {
+		return value, true
+	}
+	switch v := value.(type) {
+	case string:
+		return p.redactString(v)
+	case []string:
+		redacted := make([]string, len(v))
+		for i, s := range v {
+			r, keep := p.redactString(s)
+			if !keep {
+				return nil, false
+			}
+			redacted[i] = r
+		}
+		return redacted, true
+	case error:
+		s := v.Error()
+		r, keep := p.redactString(s)
+		if !keep || r == s {
+			return value, keep
+		}
+		return r, true
+	default:
+		return value, true
+	}
+}
+
+// Fields returns a copy of fields with the sensitive values redacted. Nested maps, such as the groups of a
+// log/slog record, are redacted too. A nil *Policy returns fields as it is.
+func (p *Policy) Fields(fields map[string]any) map[string]any {
+	if p == nil {
+		return fields
+	}
+
+	redacted := make(map[string]any, len(fields))
+	for k, v := range fields {
+		if nested, ok := v.(map[string]any); ok && !p.deniedKey(k) {
+			redacted[k] = p.Fields(nested)
+			continue
+		}
+		if v, keep := p.Value(k, v); keep {
+			redacted[k] = v
+		}
+	}
+	return redacted
+}
+
+// Attributes returns a copy of attrs with the sensitive values redacted. A nil *Policy returns attrs as it is.
+func (p *Policy) Attributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	if p == nil || len(attrs) == 0 {
+		return attrs
+	}
+
+	redacted := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var (
+			v    any
+			keep bool
+		)
+		if p.deniedKey(string(a.Key)) {
+			v, keep = p.Value(string(a.Key), a.Value.Emit())
+		} else {
+			v, keep = p.Value(string(a.Key), a.Value.AsInterface())
+		}
+		if !keep {
+			continue
+		}
+		switch v := v.(type) {
+		case string:
+			redacted = append(redacted, a.Key.String(v))
+		case []string:
+			redacted = append(redacted, a.Key.StringSlice(v))
+		default:
+			redacted = append(redacted, a)
+		}
+	}
+	return redacted
+}
+
+// deniedKey reports whether key, or its last dot-separated segment, is deny-listed.
+func (p *Policy) deniedKey(key string) bool {
+	if len(p.keys) == 0 {
+		return false
+	}
+	key = strings.ToLower(key)
+	if p.keys[key] {
+		return true
+	}
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		return p.keys[key[i+1:]]
+	}
+	return false
+}
+
+// redactString applies the matchers to s, and returns false when the field must be dropped.
+func (p *Policy) redactString(s string) (string, bool) {
+	for _, m := range p.matchers {
+		dropped := false
+		s = m.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if m.Validate != nil && !m.Validate(match) {
+				return match
+			}
+			switch m.Action {
+			case ActionDrop:
+				dropped = true
+				return match
+			case ActionHash:
+				return p.hash(match)
+			default:
+				return Mask
+			}
+		})
+		if dropped {
+			return "", false
+		}
+	}
+	return s, true
+}
+
+// hash returns the truncated, hex-encoded SHA-256, or HMAC-SHA256 when a hash key is set, of s.
+func (p *Policy) hash(s string) string {
+	var sum []byte
+	if len(p.hashKey) > 0 {
+		mac := hmac.New(sha256.New, p.hashKey)
+		mac.Write([]byte(s))
+		sum = mac.Sum(nil)
+	} else {
+		h := sha256.Sum256([]byte(s))
+		sum = h[:]
+	}
+	return hashPrefix + hex.EncodeToString(sum)[:hashLength]
+}
+
+// luhn reports whether the digits of s have a valid Luhn check digit. Spaces and dashes are ignored.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
@@ -0,0 +1,211 @@
+// Package telemetry sets up logging, tracing and metrics together, and shuts them down in the right order.
+//
+// The logging, tracing and metrics packages can still be used on their own; Setup initializes them with the same
+// service name, version and environment, and returns a [Handle] whose Shutdown flushes and stops all three.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/metrics"
+	"github.com/twistingmercury/telemetry/v2/redact"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Config are the settings used by [Setup].
+type Config struct {
+	// ServiceName is the name of the service. It is required.
+	ServiceName string
+	// ServiceVersion is the version of the service.
+	ServiceVersion string
+	// Environment is the environment the service runs in, such as `production`.
+	Environment string
+	// ResourceAttributes are additional attributes set on the resource of the spans and of the log records sent to
+	// LogExporter, such as `k8s.pod.name`.
+	ResourceAttributes []attribute.KeyValue
+	// Redaction, when set, redacts the sensitive values of the log fields and of the span attributes, so the same
+	// policy applies to both. Create it with [redact.New].
+	Redaction *redact.Policy
+
+	// LogLevel is the minimum level written by the default logger, such as `debug` or `warn`, as parsed by
+	// [zerolog.ParseLevel] regardless of case. The default, when it is empty, is `info`.
+	LogLevel string
+	// LogComponentLevels are the levels of the loggers returned by [logging.Component] for the named components,
+	// overriding LogLevel.
+	LogComponentLevels map[string]zerolog.Level
+	// LogSampling, when set, limits how many messages with the same level and text are written in each interval.
+	LogSampling *logging.SamplingOptions
+	// LogWriter is where the JSON log lines are written. The default is os.Stdout, unless LogExporter is set.
+	LogWriter io.Writer
+	// LogAsync, when set, writes the log lines to LogWriter from a goroutine through a [logging.AsyncWriter]. Shutdown
+	// writes the queued lines.
+	LogAsync *logging.AsyncWriterOptions
+	// LogExporter, when set, also sends every log message through the OpenTelemetry Logs SDK to the exporter.
+	LogExporter sdklog.Exporter
+
+	// TraceExporter is the exporter the spans are sent to. When it is nil, the exporter selected by
+	// TraceExporterOptions is created; tracing is not initialized when neither is set.
+	TraceExporter sdktrace.SpanExporter
+	// TraceExporterOptions selects and configures the exporter created with [tracing.NewExporter] when
+	// TraceExporter is nil, such as an OTLP exporter.
+	TraceExporterOptions tracing.ExporterOptions
+	// Sampler selects the spans that are sampled. The default is parent-based, always on.
+	Sampler tracing.SamplerOptions
+	// Propagators are the formats used to extract and inject the trace context. The default is
+	// [tracing.DefaultPropagators].
+	Propagators []tracing.Propagator
+
+	// MetricsNamespace is the namespace of the metrics. Metrics are not initialized when it is empty.
+	MetricsNamespace string
+	// MetricsPort is the port the metrics are published on. The default is 9090.
+	MetricsPort string
+	// LogLevelPath, when set, mounts the [logging.LevelHandler] of the default logger on the metrics server at the
+	// path, such as `/loglevel`, so the log level can be changed at runtime. It requires MetricsNamespace; Setup
+	// returns an error when it is set without it.
+	LogLevelPath string
+}
+
+// Handle is returned by [Setup] to shut down the signals it initialized.
+type Handle struct {
+	tracing bool
+	metrics bool
+	// logger is the logger created by Setup, which is shut down even if another logger became the default since.
+	logger *logging.Logger
+
+	once sync.Once
+	err  error
+}
+
+// Setup initializes, in order, the default logger, tracing (when cfg.TraceExporter or cfg.TraceExporterOptions.Type
+// is set) and metrics (when cfg.MetricsNamespace is set), and publishes the metrics for scraping. If any of them
+// fails, the ones already initialized are shut down and the error is returned.
+//
+// Setup only uses cfg; use [LoadEnv] or [SetupFromEnv] to also read the standard OpenTelemetry environment variables.
+func Setup(ctx context.Context, cfg Config) (*Handle, error) {
+	if cfg.ServiceName == "" {
+		return nil, errors.New("service name is required")
+	}
+	if cfg.LogLevelPath != "" && cfg.MetricsNamespace == "" {
+		return nil, errors.New("the log level path requires the metrics namespace")
+	}
+
+	level := zerolog.InfoLevel
+	if cfg.LogLevel != "" {
+		var err error
+		if level, err = zerolog.ParseLevel(strings.ToLower(cfg.LogLevel)); err != nil || level == zerolog.NoLevel {
+			return nil, fmt.Errorf("invalid log level: `%s`", cfg.LogLevel)
+		}
+	}
+
+	writer := cfg.LogWriter
+	if writer == nil && cfg.LogExporter == nil {
+		writer = os.Stdout
+	}
+
+	h := &Handle{}
+
+	l, err := logging.New(logging.Options{
+		Level:              level,
+		ComponentLevels:    cfg.LogComponentLevels,
+		Sampling:           cfg.LogSampling,
+		Writer:             writer,
+		Async:              cfg.LogAsync,
+		Exporter:           cfg.LogExporter,
+		ServiceName:        cfg.ServiceName,
+		ServiceVersion:     cfg.ServiceVersion,
+		Environment:        cfg.Environment,
+		ResourceAttributes: cfg.ResourceAttributes,
+		Redaction:          cfg.Redaction,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize logging: %w", err)
+	}
+	logging.SetDefault(l)
+	h.logger = l
+
+	exporter := cfg.TraceExporter
+	if exporter == nil && cfg.TraceExporterOptions.Type != "" {
+		if exporter, err = tracing.NewExporter(ctx, cfg.TraceExporterOptions); err != nil {
+			_ = h.Shutdown(ctx)
+			return nil, fmt.Errorf("failed to create the trace exporter: %w", err)
+		}
+	}
+
+	if exporter != nil {
+		err = tracing.InitializeWithOptions(tracing.Options{
+			Exporter:           exporter,
+			ResourceAttributes: cfg.ResourceAttributes,
+			ServiceName:        cfg.ServiceName,
+			ServiceVersion:     cfg.ServiceVersion,
+			Environment:        cfg.Environment,
+			Sampler:            cfg.Sampler,
+			Propagators:        cfg.Propagators,
+			Redaction:          cfg.Redaction,
+		})
+		if err != nil {
+			_ = h.Shutdown(ctx)
+			return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+		}
+		h.tracing = true
+	}
+
+	if cfg.MetricsNamespace != "" {
+		if cfg.MetricsPort == "" {
+			err = metrics.Initialize(ctx, cfg.MetricsNamespace, cfg.ServiceName)
+		} else {
+			err = metrics.InitializeWithPort(ctx, cfg.MetricsPort, cfg.MetricsNamespace, cfg.ServiceName)
+		}
+		if err != nil {
+			_ = h.Shutdown(ctx)
+			return nil, fmt.Errorf("failed to initialize metrics: %w", err)
+		}
+		if cfg.LogLevelPath != "" {
+			if err = metrics.Handle(cfg.LogLevelPath, logging.LevelHandler(logging.LevelHandlerOptions{})); err != nil {
+				_ = h.Shutdown(ctx)
+				return nil, fmt.Errorf("failed to mount the log level handler: %w", err)
+			}
+		}
+		metrics.Publish()
+		h.metrics = true
+	}
+
+	return h, nil
+}
+
+// Shutdown flushes the buffered spans and shuts down tracing, stops the metrics server, and then flushes and shuts
+// down the logger created by Setup, even if it is no longer the default logger, so the signals recorded while shutting
+// down are still logged. All three are shut down even when one of them fails; the errors are joined. Calling Shutdown
+// more than once returns the result of the first call.
+func (h *Handle) Shutdown(ctx context.Context) error {
+	h.once.Do(func() {
+		var errs []error
+		if h.tracing {
+			if err := tracing.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to shut down tracing: %w", err))
+			}
+		}
+		if h.metrics {
+			if err := metrics.ShutdownWithContext(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to shut down metrics: %w", err))
+			}
+		}
+		if h.logger != nil {
+			if err := h.logger.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to shut down logging: %w", err))
+			}
+		}
+		h.err = errors.Join(errs...)
+	})
+	return h.err
+}
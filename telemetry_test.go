@@ -0,0 +1,231 @@
+package telemetry_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const metricsPort = "9093"
+
+var errExporterShutdown = errors.New("exporter shutdown failed")
+
+// failingLogExporter fails to shut down.
+type failingLogExporter struct{}
+
+func (failingLogExporter) Export(context.Context, []sdklog.Record) error { return nil }
+func (failingLogExporter) Shutdown(context.Context) error                { return errExporterShutdown }
+func (failingLogExporter) ForceFlush(context.Context) error              { return nil }
+
+// recordingExporter keeps the exported spans after it is shut down, unlike tracetest.InMemoryExporter.
+type recordingExporter struct {
+	mu       sync.Mutex
+	names    []string
+	shutdown bool
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range spans {
+		e.names = append(e.names, s.Name())
+	}
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+func (e *recordingExporter) state() ([]string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.names...), e.shutdown
+}
+
+func metricsReachable() bool {
+	resp, err := http.Get("http://localhost:" + metricsPort + "/metrics")
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func TestSetup(t *testing.T) {
+	exporter := &recordingExporter{}
+	logs := &bytes.Buffer{}
+
+	h, err := telemetry.Setup(context.Background(), telemetry.Config{
+		ServiceName:      "test-service",
+		ServiceVersion:   "1.0.0",
+		Environment:      "unit-test",
+		LogWriter:        logs,
+		TraceExporter:    exporter,
+		MetricsNamespace: "unit",
+		MetricsPort:      metricsPort,
+		LogLevelPath:     "/loglevel",
+	})
+	require.NoError(t, err)
+
+	ctx, span := tracing.Start(context.Background(), "work", oteltrace.SpanKindInternal)
+	logging.Info(ctx, "working")
+	span.End()
+
+	assert.Contains(t, logs.String(), `"service":"test-service"`)
+	assert.Contains(t, logs.String(), span.SpanContext().TraceID().String(), "logging should be correlated with tracing")
+	require.Eventually(t, metricsReachable, time.Second, 10*time.Millisecond, "the metrics should be published")
+
+	resp, err := http.Get("http://localhost:" + metricsPort + "/loglevel")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the log level handler should be mounted")
+
+	require.NoError(t, h.Shutdown(context.Background()))
+
+	names, shutdown := exporter.state()
+	assert.Equal(t, []string{"work"}, names, "buffered spans should be flushed")
+	assert.True(t, shutdown)
+	assert.False(t, metricsReachable(), "the metrics server should be stopped")
+	assert.NoError(t, h.Shutdown(context.Background()), "Shutdown should be safe to call again")
+}
+
+func TestSetupRequiresServiceName(t *testing.T) {
+	_, err := telemetry.Setup(context.Background(), telemetry.Config{LogWriter: &bytes.Buffer{}})
+	assert.Error(t, err)
+}
+
+func TestSetupLogLevel(t *testing.T) {
+	for _, tt := range []struct {
+		level string
+		debug bool
+	}{
+		{"", false},
+		{"info", false},
+		{"DEBUG", true},
+	} {
+		logs := &bytes.Buffer{}
+		h, err := telemetry.Setup(context.Background(), telemetry.Config{
+			ServiceName: "test-service",
+			LogWriter:   logs,
+			LogLevel:    tt.level,
+		})
+		require.NoError(t, err)
+
+		logging.Debug(context.Background(), "debugging")
+		logging.Info(context.Background(), "working")
+		require.NoError(t, h.Shutdown(context.Background()))
+
+		assert.Contains(t, logs.String(), "working", "level %q", tt.level)
+		assert.Equal(t, tt.debug, strings.Contains(logs.String(), "debugging"), "level %q", tt.level)
+	}
+
+	_, err := telemetry.Setup(context.Background(), telemetry.Config{
+		ServiceName: "test-service",
+		LogWriter:   &bytes.Buffer{},
+		LogLevel:    "loud",
+	})
+	assert.EqualError(t, err, "invalid log level: `loud`")
+}
+
+func TestSetupLogLevelPathRequiresMetrics(t *testing.T) {
+	_, err := telemetry.Setup(context.Background(), telemetry.Config{
+		ServiceName:  "test-service",
+		LogWriter:    &bytes.Buffer{},
+		LogLevelPath: "/loglevel",
+	})
+	assert.EqualError(t, err, "the log level path requires the metrics namespace")
+}
+
+func TestSetupFailureShutsDownInitializedSignals(t *testing.T) {
+	exporter := &recordingExporter{}
+
+	_, err := telemetry.Setup(context.Background(), telemetry.Config{
+		ServiceName:      "test-service",
+		LogWriter:        &bytes.Buffer{},
+		TraceExporter:    exporter,
+		MetricsNamespace: "unit",
+		MetricsPort:      "80",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "metrics")
+
+	_, shutdown := exporter.state()
+	assert.True(t, shutdown, "tracing should be shut down when metrics fail to initialize")
+}
+
+func TestSetupTracingFailureShutsDownExporter(t *testing.T) {
+	exporter := &recordingExporter{}
+
+	_, err := telemetry.Setup(context.Background(), telemetry.Config{
+		ServiceName:   "test-service",
+		LogWriter:     &bytes.Buffer{},
+		TraceExporter: exporter,
+		Sampler:       tracing.SamplerOptions{Type: "unknown"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tracing")
+
+	_, shutdown := exporter.state()
+	assert.True(t, shutdown, "the trace exporter should be shut down when tracing fails to initialize")
+}
+
+func TestShutdownAfterSetDefault(t *testing.T) {
+	defer logging.SetDefault(logging.Default())
+
+	h, err := telemetry.Setup(context.Background(), telemetry.Config{
+		ServiceName: "test-service",
+		LogExporter: failingLogExporter{},
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	other, err := logging.New(logging.Options{Writer: &buf, Async: &logging.AsyncWriterOptions{}})
+	require.NoError(t, err)
+	logging.SetDefault(other)
+
+	err = h.Shutdown(context.Background())
+	assert.ErrorIs(t, err, errExporterShutdown, "the logger created by Setup should be shut down")
+
+	other.Info(context.Background(), "still written")
+	require.NoError(t, other.ForceFlush(context.Background()))
+	assert.Contains(t, buf.String(), "still written", "the default logger set after Setup should not be shut down")
+	require.NoError(t, other.Shutdown(context.Background()))
+}
+
+func TestShutdownJoinsErrors(t *testing.T) {
+	exporter := &recordingExporter{}
+	h, err := telemetry.Setup(context.Background(), telemetry.Config{
+		ServiceName:      "test-service",
+		LogExporter:      failingLogExporter{},
+		TraceExporter:    exporter,
+		MetricsNamespace: "unit",
+		MetricsPort:      metricsPort,
+	})
+	require.NoError(t, err)
+	require.Eventually(t, metricsReachable, time.Second, 10*time.Millisecond)
+
+	err = h.Shutdown(context.Background())
+	assert.ErrorIs(t, err, errExporterShutdown)
+	assert.Contains(t, err.Error(), "logging")
+	_, shutdown := exporter.state()
+	assert.True(t, shutdown, "tracing should be shut down even though logging failed")
+	assert.False(t, metricsReachable(), "the metrics server should be stopped even though logging failed")
+}
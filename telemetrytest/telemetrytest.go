@@ -0,0 +1,304 @@
+// Package telemetrytest initializes logging, tracing and metrics against in-memory sinks, so tests can assert on the
+// messages logged, the spans ended and the metrics recorded by the code under test:
+//
+//	func TestPlaceOrder(t *testing.T) {
+//		tel := telemetrytest.New(t)
+//
+//		placeOrder(context.Background(), 42)
+//
+//		tel.AssertLogged(zerolog.InfoLevel, "order placed", logging.KeyValue{Key: "order.id", Value: 42})
+//		require.NotNil(t, tel.FindSpan("place order"))
+//		tel.AssertCounter("test_orders_total", map[string]string{"status": "ok"}, 1)
+//	}
+//
+// The logging, tracing and metrics packages keep their state in package variables, so tests using a [Recorder]
+// cannot run in parallel with each other. The state is reset when the test ends.
+package telemetrytest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/metrics"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// The values the signals are initialized with by [New].
+const (
+	ServiceName    = "test-service"
+	ServiceVersion = "1.0.0"
+	Environment    = "test"
+	// Namespace is the namespace of the metrics, so a counter named `orders_total` is `test_orders_total`.
+	Namespace = "test"
+)
+
+// metricsPort is the port passed to metrics.InitializeWithPort. The metrics are never published, so it is not used.
+const metricsPort = "9090"
+
+// fatalField marks the messages logged by Fatal, which are written at the error level.
+const fatalField = "is-fatal"
+
+// Recorder records the log messages, spans and metrics of a test. Create it with [New].
+type Recorder struct {
+	t     testing.TB
+	logs  *syncBuffer
+	spans *tracetest.InMemoryExporter
+}
+
+// New initializes the default logger at the trace level, tracing with an always-on sampler, and metrics with
+// [Namespace], all recording in memory. When the test ends, tracing and metrics are shut down and the previous default
+// logger, tracer provider and propagator are restored.
+func New(t testing.TB) *Recorder {
+	t.Helper()
+
+	r := &Recorder{t: t, logs: &syncBuffer{}, spans: tracetest.NewInMemoryExporter()}
+
+	previousLogger := logging.Default()
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		ctx := context.Background()
+		_ = tracing.Shutdown(ctx)
+		_ = metrics.ShutdownWithContext(ctx)
+		logging.SetDefault(previousLogger)
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	l, err := logging.New(logging.Options{
+		Level:          zerolog.TraceLevel,
+		Writer:         r.logs,
+		ServiceName:    ServiceName,
+		ServiceVersion: ServiceVersion,
+		Environment:    Environment,
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize logging: %s", err)
+	}
+	logging.SetDefault(l)
+
+	err = tracing.InitializeWithOptions(tracing.Options{
+		Exporter:       r.spans,
+		ServiceName:    ServiceName,
+		ServiceVersion: ServiceVersion,
+		Environment:    Environment,
+		Sampler:        tracing.SamplerOptions{Type: tracing.SamplerAlwaysOn},
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize tracing: %s", err)
+	}
+
+	if err = metrics.InitializeWithPort(context.Background(), metricsPort, Namespace, ServiceName); err != nil {
+		t.Fatalf("failed to initialize metrics: %s", err)
+	}
+	return r
+}
+
+// Reset discards the log messages and spans recorded so far. Metrics are not reset.
+func (r *Recorder) Reset() {
+	_ = tracing.ForceFlush(context.Background())
+	r.logs.Reset()
+	r.spans.Reset()
+}
+
+// LogLines returns the log messages written so far, decoded from JSON.
+func (r *Recorder) LogLines() []map[string]any {
+	r.t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(r.logs.String(), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			r.t.Fatalf("the log line is not JSON: %s: %s", err, line)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+// AssertLogged checks that a message was logged at level with msg and at least fields, and reports a test error
+// listing the messages logged otherwise. Use zerolog.FatalLevel for the messages logged by Fatal. The field values
+// are compared after encoding them to JSON, so an int matches the number it was logged as, and an error matches its
+// message.
+func (r *Recorder) AssertLogged(level zerolog.Level, msg string, fields ...logging.KeyValue) bool {
+	r.t.Helper()
+
+	lines := r.LogLines()
+	for _, line := range lines {
+		if line[zerolog.MessageFieldName] == msg && hasLevel(line, level) && hasFields(line, fields) {
+			return true
+		}
+	}
+
+	var logged strings.Builder
+	for _, line := range lines {
+		b, _ := json.Marshal(line)
+		logged.WriteString("\n\t" + string(b))
+	}
+	r.t.Errorf("no %s message %q with fields %s was logged; the messages logged are:%s",
+		level, msg, formatFields(fields), logged.String())
+	return false
+}
+
+func hasLevel(line map[string]any, level zerolog.Level) bool {
+	fatal := line[fatalField] == "true"
+	if level == zerolog.FatalLevel {
+		return fatal
+	}
+	return !fatal && line[zerolog.LevelFieldName] == level.String()
+}
+
+func hasFields(line map[string]any, fields []logging.KeyValue) bool {
+	for _, f := range fields {
+		v, ok := line[f.Key]
+		if !ok || !equalJSON(v, f.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalJSON reports whether the decoded JSON value logged is the value expected once encoded to JSON.
+func equalJSON(logged, expected any) bool {
+	if err, ok := expected.(error); ok {
+		expected = err.Error()
+	}
+	b, err := json.Marshal(expected)
+	if err != nil {
+		return false
+	}
+	var decoded any
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return false
+	}
+	a, _ := json.Marshal(logged)
+	b, _ = json.Marshal(decoded)
+	return bytes.Equal(a, b)
+}
+
+func formatFields(fields []logging.KeyValue) string {
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		parts = append(parts, fmt.Sprintf("%s=%v", f.Key, f.Value))
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// Spans returns the spans ended so far, flushing the spans buffered by the batch span processor first.
+func (r *Recorder) Spans() tracetest.SpanStubs {
+	r.t.Helper()
+
+	if err := tracing.ForceFlush(context.Background()); err != nil {
+		r.t.Fatalf("failed to flush the spans: %s", err)
+	}
+	return r.spans.GetSpans()
+}
+
+// FindSpan returns the first span ended with name, or nil when there is none.
+func (r *Recorder) FindSpan(name string) *tracetest.SpanStub {
+	r.t.Helper()
+
+	spans := r.Spans()
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+// AssertCounter checks that the counter with the full name, such as `test_orders_total`, and labels has value, and
+// reports a test error otherwise. The counter can have more labels than the ones given.
+func (r *Recorder) AssertCounter(name string, labels map[string]string, value float64) bool {
+	r.t.Helper()
+
+	families, err := metrics.Registry().Gather()
+	if err != nil {
+		r.t.Errorf("failed to gather the metrics: %s", err)
+		return false
+	}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		if family.GetType() != dto.MetricType_COUNTER {
+			r.t.Errorf("%s is a %s, not a counter", name, strings.ToLower(family.GetType().String()))
+			return false
+		}
+		var found []string
+		for _, m := range family.GetMetric() {
+			if !hasLabels(m, labels) {
+				found = append(found, formatLabels(m.GetLabel()))
+				continue
+			}
+			if got := m.GetCounter().GetValue(); got != value {
+				r.t.Errorf("counter %s%s is %v, expected %v", name, formatLabels(m.GetLabel()), got, value)
+				return false
+			}
+			return true
+		}
+		r.t.Errorf("counter %s has no labels %v; the labels recorded are %s", name, labels, strings.Join(found, ", "))
+		return false
+	}
+
+	r.t.Errorf("no counter %s was registered", name)
+	return false
+}
+
+func hasLabels(m *dto.Metric, labels map[string]string) bool {
+	matched := 0
+	for _, lp := range m.GetLabel() {
+		if v, ok := labels[lp.GetName()]; ok && v == lp.GetValue() {
+			matched++
+		}
+	}
+	return matched == len(labels)
+}
+
+func formatLabels(labels []*dto.LabelPair) string {
+	parts := make([]string, 0, len(labels))
+	for _, lp := range labels {
+		parts = append(parts, fmt.Sprintf("%s=%q", lp.GetName(), lp.GetValue()))
+	}
+	sort.Strings(parts)
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// syncBuffer is a [bytes.Buffer] that can be written by several goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
@@ -0,0 +1,164 @@
+package telemetrytest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/logging"
+	"github.com/twistingmercury/telemetry/v2/metrics"
+	"github.com/twistingmercury/telemetry/v2/telemetrytest"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// recordingT records the errors reported by the assertions, so their failures can be tested.
+type recordingT struct {
+	*testing.T
+	errors []string
+}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+// placeOrder is the code under test.
+func placeOrder(ctx context.Context, orders *prometheus.CounterVec, id int, err error) {
+	ctx, span := tracing.Start(ctx, "place order", oteltrace.SpanKindInternal, attribute.Int("order.id", id))
+	defer span.End()
+
+	if err != nil {
+		logging.Error(ctx, err, "order failed", logging.KeyValue{Key: "order.id", Value: id})
+		orders.WithLabelValues("failed").Inc()
+		return
+	}
+	logging.Info(ctx, "order placed", logging.KeyValue{Key: "order.id", Value: id}, logging.KeyValue{Key: "paid", Value: true})
+	orders.WithLabelValues("ok").Inc()
+}
+
+func newOrdersCounter() *prometheus.CounterVec {
+	orders := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace(),
+		Name:      "orders_total",
+	}, []string{"status"})
+	metrics.RegisterMetrics(orders)
+	return orders
+}
+
+func TestRecorder(t *testing.T) {
+	tel := telemetrytest.New(t)
+	orders := newOrdersCounter()
+
+	placeOrder(context.Background(), orders, 42, nil)
+	placeOrder(context.Background(), orders, 43, nil)
+	placeOrder(context.Background(), orders, 44, errors.New("card declined"))
+
+	tel.AssertLogged(zerolog.InfoLevel, "order placed", logging.KeyValue{Key: "order.id", Value: 42})
+	tel.AssertLogged(zerolog.InfoLevel, "order placed", logging.KeyValue{Key: "order.id", Value: 43}, logging.KeyValue{Key: "paid", Value: true})
+	tel.AssertLogged(zerolog.ErrorLevel, "order failed", logging.KeyValue{Key: "error", Value: errors.New("card declined")})
+	assert.Len(t, tel.LogLines(), 3)
+
+	span := tel.FindSpan("place order")
+	require.NotNil(t, span)
+	assert.Contains(t, span.Attributes, attribute.Int("order.id", 42))
+	assert.Len(t, tel.Spans(), 3)
+	assert.Nil(t, tel.FindSpan("missing"))
+
+	tel.AssertCounter("test_orders_total", map[string]string{"status": "ok"}, 2)
+	tel.AssertCounter("test_orders_total", map[string]string{"status": "failed"}, 1)
+
+	tel.Reset()
+	assert.Empty(t, tel.LogLines())
+	assert.Empty(t, tel.Spans())
+	tel.AssertCounter("test_orders_total", map[string]string{"status": "ok"}, 2)
+}
+
+func TestRecorderFatalLevel(t *testing.T) {
+	rt := &recordingT{T: t}
+	tel := telemetrytest.New(rt)
+	logging.Error(context.Background(), errors.New("boom"), "not fatal")
+
+	assert.False(t, tel.AssertLogged(zerolog.FatalLevel, "not fatal"), "an error should not match the fatal level")
+	assert.True(t, tel.AssertLogged(zerolog.ErrorLevel, "not fatal"))
+}
+
+func TestRecorderFailures(t *testing.T) {
+	rt := &recordingT{T: t}
+	tel := telemetrytest.New(rt)
+	orders := newOrdersCounter()
+	placeOrder(context.Background(), orders, 42, nil)
+
+	tests := []struct {
+		name   string
+		assert func() bool
+		want   string
+	}{
+		{"message", func() bool { return tel.AssertLogged(zerolog.InfoLevel, "order shipped") }, `no info message "order shipped"`},
+		{"level", func() bool { return tel.AssertLogged(zerolog.WarnLevel, "order placed") }, `"message":"order placed"`},
+		{"field", func() bool {
+			return tel.AssertLogged(zerolog.InfoLevel, "order placed", logging.KeyValue{Key: "order.id", Value: "42"})
+		}, "order.id=42"},
+		{"counter", func() bool { return tel.AssertCounter("test_shipments_total", nil, 1) }, "no counter test_shipments_total"},
+		{"labels", func() bool { return tel.AssertCounter("test_orders_total", map[string]string{"status": "lost"}, 1) }, `{status="ok"}`},
+		{"value", func() bool { return tel.AssertCounter("test_orders_total", map[string]string{"status": "ok"}, 2) }, "is 1, expected 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt.errors = nil
+			assert.False(t, tt.assert())
+			require.Len(t, rt.errors, 1)
+			assert.Contains(t, rt.errors[0], tt.want)
+		})
+	}
+}
+
+func TestRecorderRestoresGlobals(t *testing.T) {
+	previousLogger := logging.Default()
+	previousProvider := otel.GetTracerProvider()
+
+	t.Run("recorder", func(t *testing.T) {
+		telemetrytest.New(t)
+		assert.NotSame(t, previousLogger, logging.Default())
+		assert.NotEqual(t, previousProvider, otel.GetTracerProvider())
+	})
+
+	assert.Same(t, previousLogger, logging.Default())
+	assert.Equal(t, previousProvider, otel.GetTracerProvider())
+}
+
+func TestRecorderRestoresTracing(t *testing.T) {
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	defer func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	}()
+
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetTextMapPropagator(propagation.Baggage{})
+
+	t.Run("recorder", func(t *testing.T) {
+		telemetrytest.New(t)
+	})
+
+	ctx, span := tracing.Start(context.Background(), "after cleanup", oteltrace.SpanKindInternal)
+	span.End()
+	require.Len(t, spans.Ended(), 1, "tracing.Start should use the restored tracer provider")
+	assert.Equal(t, "after cleanup", spans.Ended()[0].Name())
+
+	carrier := propagation.MapCarrier{}
+	tracing.InjectContext(ctx, carrier)
+	assert.Empty(t, carrier.Get("traceparent"), "tracing.InjectContext should use the restored propagator")
+}
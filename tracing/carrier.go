@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// HTTPHeaderCarrier adapts an [http.Header] to be used with [InjectContext] and [ExtractContext].
+type HTTPHeaderCarrier = propagation.HeaderCarrier
+
+// MapCarrier adapts a map[string]string to be used with [InjectContext] and [ExtractContext].
+type MapCarrier = propagation.MapCarrier
+
+// NewHTTPHeaderCarrier returns a carrier for the headers of an HTTP request or response.
+func NewHTTPHeaderCarrier(h http.Header) HTTPHeaderCarrier {
+	return HTTPHeaderCarrier(h)
+}
+
+// MessageHeader is a header of a message, as used by message brokers such as Kafka.
+type MessageHeader struct {
+	Key   string
+	Value []byte
+}
+
+// MessageHeaderCarrier adapts a list of [MessageHeader] to be used with [InjectContext] and [ExtractContext].
+type MessageHeaderCarrier struct {
+	headers *[]MessageHeader
+}
+
+var _ propagation.TextMapCarrier = MessageHeaderCarrier{}
+
+// NewMessageHeaderCarrier returns a carrier for the headers of a message. Injecting the trace context updates
+// the headers in place.
+func NewMessageHeaderCarrier(headers *[]MessageHeader) MessageHeaderCarrier {
+	return MessageHeaderCarrier{headers: headers}
+}
+
+// Get returns the value of the first header with the key, or an empty string if there is none.
+func (c MessageHeaderCarrier) Get(key string) string {
+	for _, h := range *c.headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces the value of the first header with the key, or adds a header if there is none.
+func (c MessageHeaderCarrier) Set(key, value string) {
+	for i, h := range *c.headers {
+		if h.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, MessageHeader{Key: key, Value: []byte(value)})
+}
+
+// Keys returns the keys of all headers.
+func (c MessageHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, h := range *c.headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
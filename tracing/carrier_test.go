@@ -0,0 +1,78 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestMessageHeaderCarrier(t *testing.T) {
+	headers := []tracing.MessageHeader{{Key: "content-type", Value: []byte("application/json")}}
+	carrier := tracing.NewMessageHeaderCarrier(&headers)
+
+	assert.Equal(t, "application/json", carrier.Get("content-type"))
+	assert.Empty(t, carrier.Get("missing"))
+
+	carrier.Set("traceparent", "first")
+	carrier.Set("traceparent", "second")
+	assert.Equal(t, "second", carrier.Get("traceparent"))
+	assert.Equal(t, []string{"content-type", "traceparent"}, carrier.Keys())
+	assert.Len(t, headers, 2, "the headers should be updated in place")
+}
+
+func TestInjectContext(t *testing.T) {
+	err := tracing.InitializeWithOptions(tracing.Options{Exporter: tracetest.NewInMemoryExporter()})
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	ctx, span := tracing.Start(context.Background(), "producer", oteltrace.SpanKindProducer)
+	defer span.End()
+	want := span.SpanContext()
+
+	tests := []struct {
+		name    string
+		carrier propagation.TextMapCarrier
+	}{
+		{"http.Header", tracing.NewHTTPHeaderCarrier(http.Header{})},
+		{"map", tracing.MapCarrier{}},
+		{"message headers", tracing.NewMessageHeaderCarrier(&[]tracing.MessageHeader{})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracing.InjectContext(ctx, tt.carrier)
+			assert.NotEmpty(t, tt.carrier.Get("traceparent"))
+
+			got := oteltrace.SpanContextFromContext(tracing.ExtractContext(context.Background(), tt.carrier))
+			assert.Equal(t, want.TraceID(), got.TraceID())
+			assert.Equal(t, want.SpanID(), got.SpanID())
+			assert.True(t, got.IsRemote())
+		})
+	}
+}
+
+func TestInjectContextIntoRequest(t *testing.T) {
+	err := tracing.InitializeWithOptions(tracing.Options{
+		Exporter:    tracetest.NewInMemoryExporter(),
+		Propagators: []tracing.Propagator{tracing.PropagatorB3Multi},
+	})
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	ctx, span := tracing.Start(context.Background(), "client", oteltrace.SpanKindClient)
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	tracing.InjectContext(req.Context(), tracing.NewHTTPHeaderCarrier(req.Header))
+
+	assert.Equal(t, span.SpanContext().TraceID().String(), req.Header.Get("X-B3-TraceId"))
+	assert.Empty(t, req.Header.Get("traceparent"), "only the configured propagators should be injected")
+}
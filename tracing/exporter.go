@@ -0,0 +1,196 @@
+package tracing
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+// ExporterType selects the exporter created by [NewExporter].
+type ExporterType string
+
+const (
+	// ExporterOTLPGRPC sends spans to an OTLP collector with gRPC, usually on port 4317.
+	ExporterOTLPGRPC ExporterType = "otlp-grpc"
+	// ExporterOTLPHTTP sends spans to an OTLP collector as protobuf over HTTP, usually on port 4318.
+	ExporterOTLPHTTP ExporterType = "otlp-http"
+	// ExporterStdout writes spans to stdout as JSON.
+	ExporterStdout ExporterType = "stdout"
+	// ExporterFile writes spans to a file as OTLP/JSON, one span per line, with the [FileExporter] configured by
+	// [ExporterOptions.File].
+	ExporterFile ExporterType = "file"
+	// ExporterNone discards spans. The trace context is still propagated, so logs are still correlated.
+	ExporterNone ExporterType = "none"
+)
+
+// CompressionGzip is the only compression supported by the OTLP exporters.
+const CompressionGzip = "gzip"
+
+// OTLPTracesPath is the path spans are sent to by the OTLP HTTP exporter when the endpoint has none, and the path
+// appended to the base URL of a collector set for every signal, such as by `OTEL_EXPORTER_OTLP_ENDPOINT`.
+const OTLPTracesPath = "/v1/traces"
+
+// The endpoints of the OTLP exporters when [ExporterOptions.Endpoint] is empty, the defaults of the OTLP
+// specification.
+const (
+	defaultOTLPGRPCEndpoint = "http://localhost:4317"
+	defaultOTLPHTTPEndpoint = "http://localhost:4318"
+)
+
+// ExporterOptions configures the exporter created by [NewExporter]. The endpoint, headers, TLS, compression and
+// timeout settings only apply to the OTLP exporters.
+type ExporterOptions struct {
+	// Type selects the exporter. It is required.
+	Type ExporterType
+	// Endpoint is the URL of the collector, such as `http://collector:4318`. An http scheme disables TLS. The OTLP
+	// HTTP exporter sends spans to the path of the URL, or to `/v1/traces` when it has none. The default is
+	// `http://localhost:4317` for gRPC and `http://localhost:4318` for HTTP, as in the OTLP specification.
+	Endpoint string
+	// Headers are sent with every export request, such as an API key.
+	Headers map[string]string
+	// Timeout is the maximum time an export request can take. The default is 10 seconds.
+	Timeout time.Duration
+	// Compression is [CompressionGzip] to compress the export requests. The default is no compression.
+	Compression string
+	// Insecure disables TLS, even when the scheme of Endpoint is https.
+	Insecure bool
+	// TLS is the TLS configuration used to connect to the collector. When it is nil, it is created from
+	// CertificateFile, ClientCertificateFile and ClientKeyFile, or the system defaults are used.
+	TLS *tls.Config
+	// CertificateFile is the PEM file of the CA certificates used to verify the collector.
+	CertificateFile string
+	// ClientCertificateFile is the PEM file of the client certificate used for mTLS.
+	ClientCertificateFile string
+	// ClientKeyFile is the PEM file of the key of the client certificate.
+	ClientKeyFile string
+	// File configures the file written by [ExporterFile] and its rotation.
+	File FileExporterOptions
+}
+
+// NewExporter creates the [sdktrace.SpanExporter] selected by opts.Type, so services can switch exporters with
+// configuration alone.
+func NewExporter(ctx context.Context, opts ExporterOptions) (sdktrace.SpanExporter, error) {
+	switch opts.Type {
+	case ExporterOTLPGRPC, ExporterOTLPHTTP:
+		return newOTLPExporter(ctx, opts)
+	case ExporterStdout:
+		return stdouttrace.New()
+	case ExporterFile:
+		return NewFileExporter(opts.File)
+	case ExporterNone:
+		return noneExporter{}, nil
+	case "":
+		return nil, errors.New("exporter type is required")
+	default:
+		return nil, fmt.Errorf("unknown exporter type: `%s`", opts.Type)
+	}
+}
+
+// newOTLPExporter creates the OTLP gRPC or HTTP exporter configured by opts.
+func newOTLPExporter(ctx context.Context, opts ExporterOptions) (sdktrace.SpanExporter, error) {
+	if opts.Endpoint == "" {
+		opts.Endpoint = defaultOTLPHTTPEndpoint
+		if opts.Type == ExporterOTLPGRPC {
+			opts.Endpoint = defaultOTLPGRPCEndpoint
+		}
+	}
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint: `%s`; the endpoint must be a URL such as http://collector:4318", opts.Endpoint)
+	}
+	if opts.Compression != "" && opts.Compression != CompressionGzip {
+		return nil, fmt.Errorf("invalid OTLP compression: `%s`; the compression must be gzip", opts.Compression)
+	}
+	tlsCfg, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Type == ExporterOTLPGRPC {
+		grpcOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpointURL(u.String())}
+		if opts.Insecure {
+			grpcOpts = append(grpcOpts, otlptracegrpc.WithInsecure())
+		} else if tlsCfg != nil {
+			grpcOpts = append(grpcOpts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsCfg)))
+		}
+		if len(opts.Headers) > 0 {
+			grpcOpts = append(grpcOpts, otlptracegrpc.WithHeaders(opts.Headers))
+		}
+		if opts.Timeout > 0 {
+			grpcOpts = append(grpcOpts, otlptracegrpc.WithTimeout(opts.Timeout))
+		}
+		if opts.Compression != "" {
+			grpcOpts = append(grpcOpts, otlptracegrpc.WithCompressor(opts.Compression))
+		}
+		return otlptracegrpc.New(ctx, grpcOpts...)
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = OTLPTracesPath
+	}
+	httpOpts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(u.String())}
+	if opts.Insecure {
+		httpOpts = append(httpOpts, otlptracehttp.WithInsecure())
+	} else if tlsCfg != nil {
+		httpOpts = append(httpOpts, otlptracehttp.WithTLSClientConfig(tlsCfg))
+	}
+	if len(opts.Headers) > 0 {
+		httpOpts = append(httpOpts, otlptracehttp.WithHeaders(opts.Headers))
+	}
+	if opts.Timeout > 0 {
+		httpOpts = append(httpOpts, otlptracehttp.WithTimeout(opts.Timeout))
+	}
+	if opts.Compression != "" {
+		httpOpts = append(httpOpts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+	}
+	return otlptracehttp.New(ctx, httpOpts...)
+}
+
+// tlsConfig returns opts.TLS, or the TLS configuration created from the certificate files. It returns nil when
+// neither is set, so the exporter uses the system defaults.
+func (opts ExporterOptions) tlsConfig() (*tls.Config, error) {
+	if opts.TLS != nil {
+		return opts.TLS, nil
+	}
+	if opts.CertificateFile == "" && opts.ClientCertificateFile == "" && opts.ClientKeyFile == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.CertificateFile != "" {
+		pem, err := os.ReadFile(opts.CertificateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA certificate: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in `%s`", opts.CertificateFile)
+		}
+	}
+	if opts.ClientCertificateFile != "" || opts.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertificateFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// noneExporter discards every span.
+type noneExporter struct{}
+
+func (noneExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error { return nil }
+
+func (noneExporter) Shutdown(context.Context) error { return nil }
@@ -0,0 +1,119 @@
+package tracing_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestNewExporterErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts tracing.ExporterOptions
+	}{
+		{"missing type", tracing.ExporterOptions{}},
+		{"unknown type", tracing.ExporterOptions{Type: "zipkin"}},
+		{"missing path", tracing.ExporterOptions{Type: tracing.ExporterFile}},
+		{"invalid endpoint", tracing.ExporterOptions{Type: tracing.ExporterOTLPGRPC, Endpoint: "collector:4317"}},
+		{"invalid compression", tracing.ExporterOptions{Type: tracing.ExporterOTLPHTTP, Endpoint: "http://collector:4318", Compression: "zstd"}},
+		{"missing certificate", tracing.ExporterOptions{Type: tracing.ExporterOTLPHTTP, Endpoint: "https://collector:4318", CertificateFile: "/does/not/exist.pem"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tracing.NewExporter(context.Background(), tt.opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewExporterDefaultEndpoint(t *testing.T) {
+	for _, typ := range []tracing.ExporterType{tracing.ExporterOTLPGRPC, tracing.ExporterOTLPHTTP} {
+		t.Run(string(typ), func(t *testing.T) {
+			exp, err := tracing.NewExporter(context.Background(), tracing.ExporterOptions{Type: typ})
+			require.NoError(t, err, "the endpoint should default to localhost")
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_ = exp.Shutdown(ctx)
+		})
+	}
+}
+
+func TestNewExporterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+
+	err := tracing.InitializeWithOptions(tracing.Options{
+		ExporterOptions: tracing.ExporterOptions{Type: tracing.ExporterFile, File: tracing.FileExporterOptions{Path: path}},
+		ServiceName:     "test-service",
+	})
+	require.NoError(t, err)
+
+	for _, name := range []string{"first", "second"} {
+		_, span := tracing.Start(context.Background(), name, oteltrace.SpanKindInternal)
+		span.End()
+	}
+	require.NoError(t, tracing.Shutdown(context.Background()))
+
+	var names []string
+	for _, line := range readLines(t, path) {
+		names = append(names, line.ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+	}
+	assert.Equal(t, []string{"first", "second"}, names)
+}
+
+func TestNewExporterNone(t *testing.T) {
+	err := tracing.InitializeWithOptions(tracing.Options{ExporterOptions: tracing.ExporterOptions{Type: tracing.ExporterNone}})
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	_, span := tracing.Start(context.Background(), "discarded", oteltrace.SpanKindInternal)
+	span.End()
+	assert.True(t, span.SpanContext().IsValid(), "spans should still be created so the trace context is propagated")
+	assert.NoError(t, tracing.ForceFlush(context.Background()))
+}
+
+func TestNewExporterOTLPHTTP(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		select {
+		case received <- r:
+		default:
+		}
+	}))
+	defer collector.Close()
+
+	err := tracing.InitializeWithOptions(tracing.Options{
+		ExporterOptions: tracing.ExporterOptions{
+			Type:        tracing.ExporterOTLPHTTP,
+			Endpoint:    collector.URL,
+			Headers:     map[string]string{"api-key": "secret"},
+			Compression: tracing.CompressionGzip,
+			Timeout:     time.Second,
+		},
+	})
+	require.NoError(t, err)
+
+	_, span := tracing.Start(context.Background(), "exported", oteltrace.SpanKindInternal)
+	span.End()
+	require.NoError(t, tracing.Shutdown(context.Background()))
+
+	select {
+	case r := <-received:
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("api-key"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the spans were not exported")
+	}
+}
@@ -0,0 +1,215 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// rotatedTimeFormat is the timestamp added to the name of a rotated file. It sorts in the order the files were rotated.
+const rotatedTimeFormat = "20060102T150405.000000000"
+
+// FileExporterOptions configures the exporter created by [NewFileExporter].
+type FileExporterOptions struct {
+	// Path is the file spans are written to. It and its directory are created if required, and it is appended to.
+	// It is required.
+	Path string
+	// MaxSize is the size in bytes after which the file is rotated. The default, 0, does not rotate by size.
+	MaxSize int64
+	// MaxAge is the time after which the file is rotated, counted from when it was opened. The default, 0, does not
+	// rotate by age.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files kept; the oldest are removed. The default, 0, keeps every file.
+	MaxBackups int
+}
+
+// FileExporter is a [sdktrace.SpanExporter] that writes each span to a file as a line of OTLP/JSON, the encoding used
+// by the OpenTelemetry Collector file exporter, so traces can be replayed or loaded into a viewer later.
+//
+// Every line is a complete TracesData message holding a single span, with its resource and instrumentation scope.
+// When the file reaches MaxSize or MaxAge it is renamed with the time it was rotated, such as
+// `spans-20240102T150405.000000000.jsonl`, and a new file is opened.
+type FileExporter struct {
+	opts FileExporterOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	stopped  bool
+}
+
+// NewFileExporter creates a [FileExporter] writing to opts.Path.
+func NewFileExporter(opts FileExporterOptions) (*FileExporter, error) {
+	if opts.Path == "" {
+		return nil, errors.New("the path is required for the file exporter")
+	}
+	if opts.MaxSize < 0 || opts.MaxAge < 0 || opts.MaxBackups < 0 {
+		return nil, errors.New("the file exporter limits cannot be negative")
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil {
+		return nil, err
+	}
+
+	e := &FileExporter{opts: opts}
+	if err := e.open(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ExportSpans writes spans to the file, one per line, rotating it first when required. Spans exported after
+// Shutdown are discarded.
+func (e *FileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopped {
+		return nil
+	}
+	for _, span := range spans {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := json.Marshal(toOTLPJSON(span))
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+
+		if e.shouldRotate(int64(len(line))) {
+			if err := e.rotate(); err != nil {
+				return err
+			}
+		}
+		n, err := e.file.Write(line)
+		e.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown closes the file.
+func (e *FileExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopped {
+		return nil
+	}
+	e.stopped = true
+	return e.file.Close()
+}
+
+// open opens opts.Path for appending and records its size.
+func (e *FileExporter) open() error {
+	f, err := os.OpenFile(e.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	e.file = f
+	e.size = info.Size()
+	e.openedAt = time.Now()
+	return nil
+}
+
+// shouldRotate reports whether the file must be rotated before a line of n bytes is written. A file is never rotated
+// while it is empty, so a span larger than MaxSize is still written.
+func (e *FileExporter) shouldRotate(n int64) bool {
+	if e.size == 0 {
+		return false
+	}
+	if e.opts.MaxSize > 0 && e.size+n > e.opts.MaxSize {
+		return true
+	}
+	return e.opts.MaxAge > 0 && time.Since(e.openedAt) >= e.opts.MaxAge
+}
+
+// rotate renames the file with the current time, opens a new one and removes the rotated files beyond MaxBackups.
+// When the file cannot be renamed it is reopened, so the following spans are still written.
+func (e *FileExporter) rotate() error {
+	if err := e.file.Close(); err != nil {
+		return err
+	}
+	name := e.rotatedName(time.Now())
+	for i := 1; fileExists(name); i++ {
+		name = e.rotatedName(time.Now().Add(time.Duration(i)))
+	}
+	renameErr := os.Rename(e.opts.Path, name)
+	if err := e.open(); err != nil {
+		return errors.Join(renameErr, err)
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	return e.removeBackups()
+}
+
+// rotatedName returns the name the file is renamed to when it is rotated at t.
+func (e *FileExporter) rotatedName(t time.Time) string {
+	ext := filepath.Ext(e.opts.Path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(e.opts.Path, ext), t.UTC().Format(rotatedTimeFormat), ext)
+}
+
+// removeBackups removes the oldest rotated files, keeping MaxBackups of them.
+func (e *FileExporter) removeBackups() error {
+	if e.opts.MaxBackups == 0 {
+		return nil
+	}
+	backups, err := e.backups()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for len(backups) > e.opts.MaxBackups {
+		errs = append(errs, os.Remove(backups[0]))
+		backups = backups[1:]
+	}
+	return errors.Join(errs...)
+}
+
+// backups returns the rotated files, oldest first.
+func (e *FileExporter) backups() ([]string, error) {
+	dir := filepath.Dir(e.opts.Path)
+	ext := filepath.Ext(e.opts.Path)
+	prefix := strings.TrimSuffix(filepath.Base(e.opts.Path), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if _, err := time.Parse(rotatedTimeFormat, stamp); err == nil {
+			backups = append(backups, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
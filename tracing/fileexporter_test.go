@@ -0,0 +1,209 @@
+package tracing_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// tracesData is the part of an OTLP/JSON TracesData line checked by the tests.
+type tracesData struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []keyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Scope struct {
+				Name string `json:"name"`
+			} `json:"scope"`
+			Spans []struct {
+				TraceID           string     `json:"traceId"`
+				SpanID            string     `json:"spanId"`
+				ParentSpanID      string     `json:"parentSpanId"`
+				Name              string     `json:"name"`
+				Kind              int        `json:"kind"`
+				StartTimeUnixNano string     `json:"startTimeUnixNano"`
+				EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+				Attributes        []keyValue `json:"attributes"`
+				Events            []struct {
+					Name string `json:"name"`
+				} `json:"events"`
+				Status struct {
+					Message string `json:"message"`
+					Code    int    `json:"code"`
+				} `json:"status"`
+			} `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+type keyValue struct {
+	Key   string                     `json:"key"`
+	Value map[string]json.RawMessage `json:"value"`
+}
+
+func newFileTracer(t *testing.T, opts tracing.FileExporterOptions) (*tracing.FileExporter, oteltrace.Tracer) {
+	exporter, err := tracing.NewFileExporter(opts)
+	require.NoError(t, err)
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "file-service"))),
+	)
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return exporter, tp.Tracer("file-test")
+}
+
+func readLines(t *testing.T, path string) []tracesData {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	var lines []tracesData
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var data tracesData
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &data), "every line should be OTLP/JSON")
+		lines = append(lines, data)
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
+
+func TestNewFileExporterErrors(t *testing.T) {
+	_, err := tracing.NewFileExporter(tracing.FileExporterOptions{})
+	assert.Error(t, err, "the path is required")
+
+	_, err = tracing.NewFileExporter(tracing.FileExporterOptions{Path: filepath.Join(t.TempDir(), "spans.jsonl"), MaxSize: -1})
+	assert.Error(t, err, "negative limits are invalid")
+}
+
+func TestFileExporterOTLPJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces", "spans.jsonl")
+	_, tracer := newFileTracer(t, tracing.FileExporterOptions{Path: path})
+
+	ctx, parent := tracer.Start(context.Background(), "parent", oteltrace.WithSpanKind(oteltrace.SpanKindServer))
+	_, child := tracer.Start(ctx, "child", oteltrace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.Int("rows", 3),
+		attribute.Bool("cached", false),
+		attribute.StringSlice("tables", []string{"users", "orders"}),
+		attribute.Float64("ratio", 0.5),
+		attribute.Float64("score", math.NaN()),
+		attribute.Float64Slice("limits", []float64{math.Inf(-1), 1, math.Inf(1)}),
+	))
+	child.AddEvent("retry")
+	child.SetStatus(codes.Error, "timeout")
+	child.End()
+	parent.End()
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 2)
+
+	rs := lines[0].ResourceSpans[0]
+	assert.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+	assert.Equal(t, "file-test", rs.ScopeSpans[0].Scope.Name)
+
+	span := rs.ScopeSpans[0].Spans[0]
+	assert.Equal(t, "child", span.Name)
+	assert.Equal(t, child.SpanContext().TraceID().String(), span.TraceID, "the trace id should be hex encoded")
+	assert.Equal(t, parent.SpanContext().SpanID().String(), span.ParentSpanID)
+	assert.Equal(t, 1, span.Kind, "internal is 1 in OTLP")
+	assert.Equal(t, 2, span.Status.Code, "error is 2 in OTLP")
+	assert.Equal(t, "timeout", span.Status.Message)
+	assert.NotEmpty(t, span.StartTimeUnixNano)
+	require.Len(t, span.Events, 1)
+	assert.Equal(t, "retry", span.Events[0].Name)
+
+	values := make(map[string]string)
+	for _, a := range span.Attributes {
+		for k, v := range a.Value {
+			values[a.Key] = k + "=" + string(v)
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"db.system": `stringValue="postgresql"`,
+		"rows":      `intValue="3"`,
+		"cached":    `boolValue=false`,
+		"tables":    `arrayValue={"values":[{"stringValue":"users"},{"stringValue":"orders"}]}`,
+		"ratio":     `doubleValue=0.5`,
+		"score":     `doubleValue="NaN"`,
+		"limits":    `arrayValue={"values":[{"doubleValue":"-Infinity"},{"doubleValue":1},{"doubleValue":"Infinity"}]}`,
+	}, values, "NaN and infinity should be encoded as strings, as in the proto3 JSON mapping")
+
+	root := lines[1].ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, 2, root.Kind, "server is 2 in OTLP")
+	assert.Empty(t, root.ParentSpanID)
+}
+
+func TestFileExporterRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "spans.jsonl")
+	_, tracer := newFileTracer(t, tracing.FileExporterOptions{Path: path, MaxSize: 1, MaxBackups: 2})
+
+	for _, name := range []string{"1", "2", "3", "4", "5"} {
+		_, span := tracer.Start(context.Background(), name)
+		span.End()
+	}
+
+	backups, err := filepath.Glob(filepath.Join(dir, "spans-*.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, backups, 2, "only MaxBackups rotated files should be kept")
+
+	var names []string
+	for _, file := range append(backups, path) {
+		lines := readLines(t, file)
+		require.Len(t, lines, 1, "every span is larger than MaxSize, so each file should hold one")
+		names = append(names, lines[0].ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+	}
+	assert.Equal(t, []string{"3", "4", "5"}, names, "the oldest files should be removed")
+}
+
+func TestFileExporterRotatesByAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "spans.jsonl")
+	_, tracer := newFileTracer(t, tracing.FileExporterOptions{Path: path, MaxAge: 50 * time.Millisecond})
+
+	_, span := tracer.Start(context.Background(), "old")
+	span.End()
+	_, span = tracer.Start(context.Background(), "same file")
+	span.End()
+	time.Sleep(60 * time.Millisecond)
+	_, span = tracer.Start(context.Background(), "new")
+	span.End()
+
+	backups, err := filepath.Glob(filepath.Join(dir, "spans-*.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Len(t, readLines(t, backups[0]), 2)
+	assert.Len(t, readLines(t, path), 1)
+}
+
+func TestFileExporterShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{}\n"), 0o644))
+
+	exporter, tracer := newFileTracer(t, tracing.FileExporterOptions{Path: path})
+	_, span := tracer.Start(context.Background(), "appended")
+	span.End()
+
+	require.NoError(t, exporter.Shutdown(context.Background()))
+	assert.NoError(t, exporter.Shutdown(context.Background()), "shutting down twice should not fail")
+
+	_, span = tracer.Start(context.Background(), "discarded")
+	span.End()
+	assert.Len(t, readLines(t, path), 2, "the file should be appended to, and spans ended after shutdown discarded")
+}
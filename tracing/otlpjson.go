@@ -0,0 +1,248 @@
+package tracing
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// The types below follow the OTLP/JSON encoding of the opentelemetry.proto.trace.v1.TracesData message: field names
+// are lowerCamelCase, trace and span ids are hex strings, 64 bit integers are strings, enums are numbers, and NaN and
+// infinite doubles are the strings "NaN", "Infinity" and "-Infinity".
+
+type otlpTracesData struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	SchemaURL  string           `json:"schemaUrl,omitempty"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope     otlpScope  `json:"scope"`
+	Spans     []otlpSpan `json:"spans"`
+	SchemaURL string     `json:"schemaUrl,omitempty"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID                string         `json:"traceId"`
+	SpanID                 string         `json:"spanId"`
+	TraceState             string         `json:"traceState,omitempty"`
+	ParentSpanID           string         `json:"parentSpanId,omitempty"`
+	Flags                  uint32         `json:"flags,omitempty"`
+	Name                   string         `json:"name"`
+	Kind                   int            `json:"kind"`
+	StartTimeUnixNano      string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano        string         `json:"endTimeUnixNano"`
+	Attributes             []otlpKeyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int            `json:"droppedAttributesCount,omitempty"`
+	Events                 []otlpEvent    `json:"events,omitempty"`
+	DroppedEventsCount     int            `json:"droppedEventsCount,omitempty"`
+	Links                  []otlpLink     `json:"links,omitempty"`
+	DroppedLinksCount      int            `json:"droppedLinksCount,omitempty"`
+	Status                 otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano           string         `json:"timeUnixNano"`
+	Name                   string         `json:"name"`
+	Attributes             []otlpKeyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int            `json:"droppedAttributesCount,omitempty"`
+}
+
+type otlpLink struct {
+	TraceID                string         `json:"traceId"`
+	SpanID                 string         `json:"spanId"`
+	TraceState             string         `json:"traceState,omitempty"`
+	Attributes             []otlpKeyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int            `json:"droppedAttributesCount,omitempty"`
+}
+
+type otlpStatus struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *otlpDouble     `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+// otlpDouble is a double encoded as in the proto3 JSON mapping, which, unlike json.Marshal, supports NaN and infinity.
+type otlpDouble float64
+
+// MarshalJSON encodes NaN and infinity as strings, and the other values as JSON numbers.
+func (d otlpDouble) MarshalJSON() ([]byte, error) {
+	f := float64(d)
+	switch {
+	case math.IsNaN(f):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(f, 1):
+		return []byte(`"Infinity"`), nil
+	case math.IsInf(f, -1):
+		return []byte(`"-Infinity"`), nil
+	default:
+		return json.Marshal(f)
+	}
+}
+
+// The OTLP status codes, which differ from the values of [codes.Code].
+const (
+	otlpStatusOk    = 1
+	otlpStatusError = 2
+)
+
+// toOTLPJSON returns the OTLP/JSON TracesData holding only span, with its resource and instrumentation scope.
+func toOTLPJSON(span sdktrace.ReadOnlySpan) otlpTracesData {
+	sc := span.SpanContext()
+	s := otlpSpan{
+		TraceID:                sc.TraceID().String(),
+		SpanID:                 sc.SpanID().String(),
+		TraceState:             sc.TraceState().String(),
+		Flags:                  uint32(sc.TraceFlags()),
+		Name:                   span.Name(),
+		Kind:                   int(span.SpanKind()),
+		StartTimeUnixNano:      strconv.FormatInt(span.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:        strconv.FormatInt(span.EndTime().UnixNano(), 10),
+		Attributes:             toOTLPKeyValues(span.Attributes()),
+		DroppedAttributesCount: span.DroppedAttributes(),
+		DroppedEventsCount:     span.DroppedEvents(),
+		DroppedLinksCount:      span.DroppedLinks(),
+		Status:                 otlpStatus{Message: span.Status().Description},
+	}
+	if parent := span.Parent(); parent.HasSpanID() {
+		s.ParentSpanID = parent.SpanID().String()
+	}
+	switch span.Status().Code {
+	case codes.Ok:
+		s.Status.Code = otlpStatusOk
+	case codes.Error:
+		s.Status.Code = otlpStatusError
+	}
+	for _, e := range span.Events() {
+		s.Events = append(s.Events, otlpEvent{
+			TimeUnixNano:           strconv.FormatInt(e.Time.UnixNano(), 10),
+			Name:                   e.Name,
+			Attributes:             toOTLPKeyValues(e.Attributes),
+			DroppedAttributesCount: e.DroppedAttributeCount,
+		})
+	}
+	for _, l := range span.Links() {
+		s.Links = append(s.Links, otlpLink{
+			TraceID:                l.SpanContext.TraceID().String(),
+			SpanID:                 l.SpanContext.SpanID().String(),
+			TraceState:             l.SpanContext.TraceState().String(),
+			Attributes:             toOTLPKeyValues(l.Attributes),
+			DroppedAttributesCount: l.DroppedAttributeCount,
+		})
+	}
+
+	return otlpTracesData{ResourceSpans: []otlpResourceSpans{{
+		Resource:  toOTLPResource(span.Resource()),
+		SchemaURL: resourceSchemaURL(span.Resource()),
+		ScopeSpans: []otlpScopeSpans{{
+			Scope:     toOTLPScope(span.InstrumentationScope()),
+			Spans:     []otlpSpan{s},
+			SchemaURL: span.InstrumentationScope().SchemaURL,
+		}},
+	}}}
+}
+
+func toOTLPResource(res *resource.Resource) otlpResource {
+	if res == nil {
+		return otlpResource{}
+	}
+	return otlpResource{Attributes: toOTLPKeyValues(res.Attributes())}
+}
+
+func resourceSchemaURL(res *resource.Resource) string {
+	if res == nil {
+		return ""
+	}
+	return res.SchemaURL()
+}
+
+func toOTLPScope(scope instrumentation.Scope) otlpScope {
+	return otlpScope{Name: scope.Name, Version: scope.Version}
+}
+
+func toOTLPKeyValues(attrs []attribute.KeyValue) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		kvs = append(kvs, otlpKeyValue{Key: string(a.Key), Value: toOTLPAnyValue(a.Value)})
+	}
+	return kvs
+}
+
+func toOTLPAnyValue(v attribute.Value) otlpAnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return otlpAnyValue{BoolValue: &b}
+	case attribute.INT64:
+		i := strconv.FormatInt(v.AsInt64(), 10)
+		return otlpAnyValue{IntValue: &i}
+	case attribute.FLOAT64:
+		f := otlpDouble(v.AsFloat64())
+		return otlpAnyValue{DoubleValue: &f}
+	case attribute.BOOLSLICE:
+		values := make([]otlpAnyValue, 0, len(v.AsBoolSlice()))
+		for _, b := range v.AsBoolSlice() {
+			values = append(values, toOTLPAnyValue(attribute.BoolValue(b)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.INT64SLICE:
+		values := make([]otlpAnyValue, 0, len(v.AsInt64Slice()))
+		for _, i := range v.AsInt64Slice() {
+			values = append(values, toOTLPAnyValue(attribute.Int64Value(i)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.FLOAT64SLICE:
+		values := make([]otlpAnyValue, 0, len(v.AsFloat64Slice()))
+		for _, f := range v.AsFloat64Slice() {
+			values = append(values, toOTLPAnyValue(attribute.Float64Value(f)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.STRINGSLICE:
+		values := make([]otlpAnyValue, 0, len(v.AsStringSlice()))
+		for _, s := range v.AsStringSlice() {
+			values = append(values, toOTLPAnyValue(attribute.StringValue(s)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	default:
+		s := v.Emit()
+		return otlpAnyValue{StringValue: &s}
+	}
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Propagator is a trace context propagation format. The values match the names used by the OTEL_PROPAGATORS
+// environment variable.
+type Propagator string
+
+const (
+	// PropagatorTraceContext is the W3C Trace Context format, using the `traceparent` and `tracestate` headers.
+	PropagatorTraceContext Propagator = "tracecontext"
+	// PropagatorBaggage is the W3C Baggage format, using the `baggage` header.
+	PropagatorBaggage Propagator = "baggage"
+	// PropagatorB3 is the B3 single header format, using the `b3` header.
+	PropagatorB3 Propagator = "b3"
+	// PropagatorB3Multi is the B3 multiple header format, using the `x-b3-*` headers.
+	PropagatorB3Multi Propagator = "b3multi"
+	// PropagatorJaeger is the Jaeger format, using the `uber-trace-id` header.
+	PropagatorJaeger Propagator = "jaeger"
+	// PropagatorXRay is the AWS X-Ray format, using the `X-Amzn-Trace-Id` header.
+	PropagatorXRay Propagator = "xray"
+	// PropagatorNone disables propagation. It adds no format, so the trace context is neither extracted nor
+	// injected unless other formats are also configured.
+	PropagatorNone Propagator = "none"
+)
+
+// DefaultPropagators are the formats used when none are configured: W3C Trace Context and W3C Baggage.
+var DefaultPropagators = []Propagator{PropagatorTraceContext, PropagatorBaggage}
+
+// NewPropagator returns a [propagation.TextMapPropagator] that extracts and injects every one of the formats.
+// When no formats are passed the [DefaultPropagators] are used.
+func NewPropagator(formats ...Propagator) (propagation.TextMapPropagator, error) {
+	if len(formats) == 0 {
+		formats = DefaultPropagators
+	}
+
+	propagators := make([]propagation.TextMapPropagator, 0, len(formats))
+	for _, format := range formats {
+		switch format {
+		case PropagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case PropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		case PropagatorB3:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case PropagatorB3Multi:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case PropagatorJaeger:
+			propagators = append(propagators, jaeger.Jaeger{})
+		case PropagatorXRay:
+			propagators = append(propagators, xray.Propagator{})
+		case PropagatorNone:
+		default:
+			return nil, fmt.Errorf("unknown propagator: `%s`", format)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
@@ -0,0 +1,120 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestNewPropagatorUnknownFormat(t *testing.T) {
+	_, err := tracing.NewPropagator(tracing.PropagatorTraceContext, "unknown")
+	assert.Error(t, err)
+
+	err = tracing.InitializeWithOptions(tracing.Options{
+		Exporter:    tracetest.NewInMemoryExporter(),
+		Propagators: []tracing.Propagator{"unknown"},
+	})
+	assert.Error(t, err)
+}
+
+func TestNewPropagatorDefault(t *testing.T) {
+	p, err := tracing.NewPropagator()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"traceparent", "tracestate", "baggage"}, p.Fields())
+}
+
+func TestNewPropagatorNone(t *testing.T) {
+	p, err := tracing.NewPropagator(tracing.PropagatorNone)
+	require.NoError(t, err)
+	assert.Empty(t, p.Fields(), "none should disable propagation")
+}
+
+func TestPropagatorRoundTrip(t *testing.T) {
+	sampled := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    testTraceID,
+		SpanID:     testSpanID,
+		TraceFlags: oteltrace.FlagsSampled,
+	})
+
+	tests := []struct {
+		format tracing.Propagator
+		header string
+	}{
+		{tracing.PropagatorTraceContext, "Traceparent"},
+		{tracing.PropagatorB3, "B3"},
+		{tracing.PropagatorB3Multi, "X-B3-Traceid"},
+		{tracing.PropagatorJaeger, "Uber-Trace-Id"},
+		{tracing.PropagatorXRay, "X-Amzn-Trace-Id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			err := tracing.InitializeWithOptions(tracing.Options{
+				Exporter:    tracetest.NewInMemoryExporter(),
+				Propagators: []tracing.Propagator{tt.format},
+			})
+			require.NoError(t, err)
+			defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+			p, err := tracing.NewPropagator(tt.format)
+			require.NoError(t, err)
+
+			carrier := propagation.HeaderCarrier{}
+			p.Inject(oteltrace.ContextWithSpanContext(context.Background(), sampled), carrier)
+			assert.NotEmpty(t, carrier.Get(tt.header), "the %s header should be injected", tt.header)
+
+			extracted := oteltrace.SpanContextFromContext(tracing.ExtractContext(context.Background(), carrier))
+			assert.True(t, extracted.IsRemote())
+			assert.Equal(t, testTraceID, extracted.TraceID())
+			assert.Equal(t, testSpanID, extracted.SpanID())
+			assert.True(t, extracted.IsSampled())
+		})
+	}
+}
+
+func TestExtractContextHeaderFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{"w3c", map[string]string{"traceparent": "00-0102030405060708090a0b0c0d0e0f10-1112131415161718-01"}},
+		{"b3 single", map[string]string{"b3": "0102030405060708090a0b0c0d0e0f10-1112131415161718-1"}},
+		{"b3 multi", map[string]string{
+			"x-b3-traceid": "0102030405060708090a0b0c0d0e0f10",
+			"x-b3-spanid":  "1112131415161718",
+			"x-b3-sampled": "1",
+		}},
+		{"jaeger", map[string]string{"uber-trace-id": "0102030405060708090a0b0c0d0e0f10:1112131415161718:0:1"}},
+		{"xray", map[string]string{"X-Amzn-Trace-Id": "Root=1-01020304-05060708090a0b0c0d0e0f10;Parent=1112131415161718;Sampled=1"}},
+	}
+
+	err := tracing.InitializeWithOptions(tracing.Options{
+		Exporter: tracetest.NewInMemoryExporter(),
+		Propagators: []tracing.Propagator{
+			tracing.PropagatorTraceContext,
+			tracing.PropagatorB3,
+			tracing.PropagatorB3Multi,
+			tracing.PropagatorJaeger,
+			tracing.PropagatorXRay,
+		},
+	})
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			carrier := propagation.MapCarrier(tt.headers)
+
+			extracted := oteltrace.SpanContextFromContext(tracing.ExtractContext(context.Background(), carrier))
+			assert.Equal(t, testTraceID, extracted.TraceID())
+			assert.Equal(t, testSpanID, extracted.SpanID())
+			assert.True(t, extracted.IsSampled())
+		})
+	}
+}
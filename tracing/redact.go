@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"github.com/twistingmercury/telemetry/v2/redact"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// NewRedactingProcessor returns a span processor that redacts the attributes of every span, of its events and of its
+// links, and the description of its status, with policy before forwarding the ended span to next. Use it to redact
+// the spans of a tracer provider created without [InitializeWithOptions]; [Options.Redaction] adds it to the provider
+// created by InitializeWithOptions.
+func NewRedactingProcessor(next sdktrace.SpanProcessor, policy *redact.Policy) sdktrace.SpanProcessor {
+	return redactingProcessor{SpanProcessor: next, policy: policy}
+}
+
+// redactingProcessor forwards the ended spans to the wrapped span processor with their attributes redacted.
+type redactingProcessor struct {
+	sdktrace.SpanProcessor
+	policy *redact.Policy
+}
+
+// OnEnd forwards s to the wrapped processor with its attributes, the attributes of its events and links, and its
+// status description redacted.
+func (p redactingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if p.policy == nil {
+		p.SpanProcessor.OnEnd(s)
+		return
+	}
+
+	events := s.Events()
+	redactedEvents := make([]sdktrace.Event, len(events))
+	for i, e := range events {
+		e.Attributes = p.policy.Attributes(e.Attributes)
+		redactedEvents[i] = e
+	}
+
+	links := s.Links()
+	redactedLinks := make([]sdktrace.Link, len(links))
+	for i, l := range links {
+		l.Attributes = p.policy.Attributes(l.Attributes)
+		redactedLinks[i] = l
+	}
+
+	// The description has no key, so only the matchers of the policy apply to it.
+	status := s.Status()
+	if v, keep := p.policy.Value("", status.Description); !keep {
+		status.Description = ""
+	} else if d, ok := v.(string); ok {
+		status.Description = d
+	}
+
+	p.SpanProcessor.OnEnd(redactedSpan{
+		ReadOnlySpan: s,
+		attributes:   p.policy.Attributes(s.Attributes()),
+		events:       redactedEvents,
+		links:        redactedLinks,
+		status:       status,
+	})
+}
+
+// redactedSpan replaces the attributes, events, links and status of an ended span with their redacted copies.
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	attributes []attribute.KeyValue
+	events     []sdktrace.Event
+	links      []sdktrace.Link
+	status     sdktrace.Status
+}
+
+// Attributes returns the redacted attributes of the span.
+func (s redactedSpan) Attributes() []attribute.KeyValue {
+	return s.attributes
+}
+
+// Events returns the events of the span with their attributes redacted.
+func (s redactedSpan) Events() []sdktrace.Event {
+	return s.events
+}
+
+// Links returns the links of the span with their attributes redacted.
+func (s redactedSpan) Links() []sdktrace.Link {
+	return s.links
+}
+
+// Status returns the status of the span with its description redacted.
+func (s redactedSpan) Status() sdktrace.Status {
+	return s.status
+}
@@ -0,0 +1,96 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/redact"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestInitializeWithOptionsRedaction(t *testing.T) {
+	policy, err := redact.New(redact.Options{
+		Keys:     []string{"password"},
+		Matchers: []redact.Matcher{redact.Email(redact.ActionHash), redact.CardNumber(redact.ActionDrop)},
+	})
+	require.NoError(t, err)
+
+	exporter := tracetest.NewInMemoryExporter()
+	err = tracing.InitializeWithOptions(tracing.Options{
+		Exporter:    exporter,
+		ServiceName: serviceName,
+		Redaction:   policy,
+	})
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	_, span := tracing.Start(context.Background(), "login", oteltrace.SpanKindServer,
+		attribute.String("user.password", "hunter2"),
+		attribute.String("user.email", "jane@example.com"))
+	span.SetAttributes(attribute.String("payment.card", "4111111111111111"), attribute.Int("order.id", 42))
+	span.AddEvent("retry", oteltrace.WithAttributes(attribute.String("password", "hunter2")))
+	span.End()
+
+	require.NoError(t, tracing.ForceFlush(context.Background()))
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+
+	attrs := spans[0].Attributes
+	assert.Contains(t, attrs, attribute.String("user.password", redact.Mask))
+	assert.Contains(t, attrs, attribute.Int("order.id", 42))
+	assert.Len(t, attrs, 3, "the card number should be dropped")
+	for _, a := range attrs {
+		if a.Key == "user.email" {
+			assert.Regexp(t, `^sha256:`, a.Value.AsString())
+		}
+	}
+	require.Len(t, spans[0].Events, 1)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("password", redact.Mask)}, spans[0].Events[0].Attributes)
+}
+
+func TestNewRedactingProcessorStatusAndLinks(t *testing.T) {
+	policy, err := redact.New(redact.Options{
+		Keys:     []string{"token"},
+		Matchers: []redact.Matcher{redact.Email(redact.ActionMask)},
+	})
+	require.NoError(t, err)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(
+		tracing.NewRedactingProcessor(sdktrace.NewSimpleSpanProcessor(exporter), policy)))
+
+	link := oteltrace.Link{
+		SpanContext: oteltrace.SpanContextFromContext(parentContext(true)),
+		Attributes:  []attribute.KeyValue{attribute.String("token", "secret"), attribute.String("queue", "orders")},
+	}
+	_, span := tp.Tracer("test").Start(context.Background(), "login", oteltrace.WithLinks(link))
+	span.SetStatus(codes.Error, "no account for jane@example.com")
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "no account for "+redact.Mask, spans[0].Status.Description)
+	require.Len(t, spans[0].Links, 1)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("token", redact.Mask), attribute.String("queue", "orders")},
+		spans[0].Links[0].Attributes)
+}
+
+func TestNewRedactingProcessorNilPolicy(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(
+		tracing.NewRedactingProcessor(sdktrace.NewSimpleSpanProcessor(exporter), nil)))
+
+	_, span := tp.Tracer("test").Start(context.Background(), "login", oteltrace.WithAttributes(attribute.String("password", "hunter2")))
+	span.End()
+
+	require.Len(t, exporter.GetSpans(), 1)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("password", "hunter2")}, exporter.GetSpans()[0].Attributes)
+}
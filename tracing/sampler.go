@@ -0,0 +1,248 @@
+package tracing
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// SamplerType selects the sampling strategy used by the tracer provider. The values match the names used by the
+// OTEL_TRACES_SAMPLER environment variable, with the addition of the rate limiting samplers.
+type SamplerType string
+
+const (
+	// SamplerAlwaysOn samples every span.
+	SamplerAlwaysOn SamplerType = "always_on"
+	// SamplerAlwaysOff samples no spans.
+	SamplerAlwaysOff SamplerType = "always_off"
+	// SamplerTraceIDRatio samples a ratio of traces, ignoring the sampling decision of the parent.
+	SamplerTraceIDRatio SamplerType = "traceidratio"
+	// SamplerRateLimiting samples up to a number of spans per second, ignoring the sampling decision of the parent.
+	SamplerRateLimiting SamplerType = "ratelimiting"
+	// SamplerParentBasedAlwaysOn follows the decision of the parent, and samples every root span.
+	SamplerParentBasedAlwaysOn SamplerType = "parentbased_always_on"
+	// SamplerParentBasedAlwaysOff follows the decision of the parent, and samples no root spans.
+	SamplerParentBasedAlwaysOff SamplerType = "parentbased_always_off"
+	// SamplerParentBasedTraceIDRatio follows the decision of the parent, and samples a ratio of root spans.
+	SamplerParentBasedTraceIDRatio SamplerType = "parentbased_traceidratio"
+	// SamplerParentBasedRateLimiting follows the decision of the parent, and samples up to a number of root spans
+	// per second.
+	SamplerParentBasedRateLimiting SamplerType = "parentbased_ratelimiting"
+)
+
+// SamplerOptions configures the sampler used by the tracer provider.
+type SamplerOptions struct {
+	// Type is the sampling strategy. The default is [SamplerParentBasedAlwaysOn].
+	Type SamplerType
+	// Ratio is the ratio of traces sampled by the trace id ratio samplers, greater than 0 and at most 1. Use
+	// [SamplerAlwaysOff] or [SamplerParentBasedAlwaysOff] to sample no traces.
+	Ratio float64
+	// SpansPerSecond is the number of spans sampled per second by the rate limiting samplers.
+	SpansPerSecond float64
+	// Rules are evaluated, in order, before the sampler selected by Type. The first rule that matches a span
+	// decides whether it is sampled. With the parent based types, the rules only apply to root spans, and the other
+	// spans follow the decision of their parent.
+	Rules []SamplingRule
+	// KeepErrors exports spans that end with an error status even if the sampler dropped them. Dropped spans are
+	// still recorded, so this adds overhead for every span that is not sampled. With the parent based types, this
+	// includes the children of unsampled parents: such an error span is exported without the rest of its trace.
+	KeepErrors bool
+}
+
+// SamplingRule samples or drops the spans that match it. A span matches when its name equals SpanName (an empty
+// SpanName matches every span) and it was started with all of the Attributes.
+//
+// Sampling decisions are made when a span starts, so rules only see the attributes passed to [Start].
+type SamplingRule struct {
+	// SpanName is the name a span must have to match. An empty SpanName matches every span.
+	SpanName string
+	// Attributes must all be present, with equal values, on a span for it to match.
+	Attributes []attribute.KeyValue
+	// Sample is true to sample matching spans, and false to drop them.
+	Sample bool
+}
+
+// NewSampler returns the [sdktrace.Sampler] described by opts. It does not apply [SamplerOptions.KeepErrors],
+// which also requires a span processor; use [InitializeWithOptions] for that.
+func NewSampler(opts SamplerOptions) (sdktrace.Sampler, error) {
+	var sampler sdktrace.Sampler
+
+	switch opts.Type {
+	case SamplerAlwaysOn:
+		sampler = sdktrace.AlwaysSample()
+	case SamplerAlwaysOff:
+		sampler = sdktrace.NeverSample()
+	case SamplerTraceIDRatio, SamplerParentBasedTraceIDRatio:
+		if opts.Ratio <= 0 || opts.Ratio > 1 {
+			return nil, fmt.Errorf("invalid sampler ratio: `%v`; the ratio must be greater than 0 and at most 1", opts.Ratio)
+		}
+		sampler = sdktrace.TraceIDRatioBased(opts.Ratio)
+	case SamplerRateLimiting, SamplerParentBasedRateLimiting:
+		if opts.SpansPerSecond <= 0 {
+			return nil, fmt.Errorf("invalid sampler rate: `%v`; the spans per second must be greater than 0", opts.SpansPerSecond)
+		}
+		sampler = newRateLimitingSampler(opts.SpansPerSecond)
+	case "", SamplerParentBasedAlwaysOn:
+		sampler = sdktrace.AlwaysSample()
+	case SamplerParentBasedAlwaysOff:
+		sampler = sdktrace.NeverSample()
+	default:
+		return nil, fmt.Errorf("unknown sampler type: `%s`", opts.Type)
+	}
+
+	if len(opts.Rules) > 0 {
+		sampler = &ruleSampler{rules: opts.Rules, fallback: sampler}
+	}
+
+	// The parent based samplers only apply the rules to root spans, so a child span never breaks the trace of its
+	// parent.
+	switch opts.Type {
+	case "", SamplerParentBasedAlwaysOn, SamplerParentBasedAlwaysOff, SamplerParentBasedTraceIDRatio, SamplerParentBasedRateLimiting:
+		sampler = sdktrace.ParentBased(sampler)
+	}
+	return sampler, nil
+}
+
+// rateLimitingSampler samples up to a number of spans per second using a token bucket.
+type rateLimitingSampler struct {
+	mu       sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newRateLimitingSampler(spansPerSecond float64) *rateLimitingSampler {
+	capacity := spansPerSecond
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &rateLimitingSampler{
+		rate:     spansPerSecond,
+		capacity: capacity,
+		tokens:   capacity,
+		last:     time.Now(),
+	}
+}
+
+// ShouldSample samples the span if a token is available.
+func (s *rateLimitingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	s.mu.Lock()
+	now := time.Now()
+	s.tokens += now.Sub(s.last).Seconds() * s.rate
+	if s.tokens > s.capacity {
+		s.tokens = s.capacity
+	}
+	s.last = now
+
+	decision := sdktrace.Drop
+	if s.tokens >= 1 {
+		s.tokens--
+		decision = sdktrace.RecordAndSample
+	}
+	s.mu.Unlock()
+
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: oteltrace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+// Description returns the description of the sampler.
+func (s *rateLimitingSampler) Description() string {
+	return fmt.Sprintf("RateLimitingSampler{%g}", s.rate)
+}
+
+// ruleSampler applies the first matching [SamplingRule], and defers to the fallback sampler otherwise.
+type ruleSampler struct {
+	rules    []SamplingRule
+	fallback sdktrace.Sampler
+}
+
+// ShouldSample returns the decision of the first matching rule, or of the fallback sampler.
+func (s *ruleSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, rule := range s.rules {
+		if !rule.matches(p) {
+			continue
+		}
+
+		decision := sdktrace.Drop
+		if rule.Sample {
+			decision = sdktrace.RecordAndSample
+		}
+		return sdktrace.SamplingResult{
+			Decision:   decision,
+			Tracestate: oteltrace.SpanContextFromContext(p.ParentContext).TraceState(),
+		}
+	}
+	return s.fallback.ShouldSample(p)
+}
+
+// Description returns the description of the sampler.
+func (s *ruleSampler) Description() string {
+	return fmt.Sprintf("RuleSampler{rules:%d,fallback:%s}", len(s.rules), s.fallback.Description())
+}
+
+// matches reports whether the span described by p matches the rule.
+func (r SamplingRule) matches(p sdktrace.SamplingParameters) bool {
+	if r.SpanName != "" && r.SpanName != p.Name {
+		return false
+	}
+
+	set := attribute.NewSet(p.Attributes...)
+	for _, want := range r.Attributes {
+		got, ok := set.Value(want.Key)
+		if !ok || got != want.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// recordingSampler records the spans dropped by the wrapped sampler, so they can be exported by the
+// errorSpanProcessor if they end with an error.
+type recordingSampler struct {
+	sdktrace.Sampler
+}
+
+// ShouldSample returns the decision of the wrapped sampler, recording spans it drops.
+func (s recordingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	res := s.Sampler.ShouldSample(p)
+	if res.Decision == sdktrace.Drop {
+		res.Decision = sdktrace.RecordOnly
+	}
+	return res
+}
+
+// errorSpanProcessor forwards sampled spans, and recorded but unsampled spans that ended with an error, to the
+// wrapped span processor.
+type errorSpanProcessor struct {
+	sdktrace.SpanProcessor
+}
+
+// OnEnd forwards s to the wrapped processor if it was sampled or ended with an error.
+func (p errorSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.SpanProcessor.OnEnd(s)
+		return
+	}
+	if s.Status().Code == codes.Error {
+		p.SpanProcessor.OnEnd(sampledSpan{ReadOnlySpan: s})
+	}
+}
+
+// sampledSpan marks an unsampled span as sampled so it is exported by the batch span processor.
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+// SpanContext returns the span context of the span with the sampled flag set.
+func (s sampledSpan) SpanContext() oteltrace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
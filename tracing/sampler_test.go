@@ -0,0 +1,224 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+var (
+	testTraceID = oteltrace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10}
+	testSpanID  = oteltrace.SpanID{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18}
+)
+
+// parentContext returns a context with a remote parent span that is sampled or not.
+func parentContext(sampled bool) context.Context {
+	var flags oteltrace.TraceFlags
+	if sampled {
+		flags = oteltrace.FlagsSampled
+	}
+	return oteltrace.ContextWithRemoteSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    testTraceID,
+		SpanID:     testSpanID,
+		TraceFlags: flags,
+		Remote:     true,
+	}))
+}
+
+func shouldSample(s sdktrace.Sampler, ctx context.Context, name string, attribs ...attribute.KeyValue) sdktrace.SamplingDecision {
+	return s.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: ctx,
+		TraceID:       testTraceID,
+		Name:          name,
+		Kind:          oteltrace.SpanKindServer,
+		Attributes:    attribs,
+	}).Decision
+}
+
+func TestNewSamplerInvalidOptions(t *testing.T) {
+	_, err := tracing.NewSampler(tracing.SamplerOptions{Type: "unknown"})
+	assert.Error(t, err)
+
+	_, err = tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerParentBasedTraceIDRatio, Ratio: 1.5})
+	assert.Error(t, err)
+
+	_, err = tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerTraceIDRatio, Ratio: -0.5})
+	assert.Error(t, err)
+
+	_, err = tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerParentBasedTraceIDRatio})
+	assert.ErrorContains(t, err, "invalid sampler ratio", "an unset ratio should not drop every trace")
+
+	_, err = tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerRateLimiting})
+	assert.Error(t, err)
+
+	err = tracing.InitializeWithOptions(tracing.Options{
+		Exporter: tracetest.NewInMemoryExporter(),
+		Sampler:  tracing.SamplerOptions{Type: "unknown"},
+	})
+	assert.Error(t, err)
+}
+
+func TestNewSamplerAlwaysAndNever(t *testing.T) {
+	on, err := tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerAlwaysOn})
+	require.NoError(t, err)
+	assert.Equal(t, sdktrace.RecordAndSample, shouldSample(on, parentContext(false), "span"))
+
+	off, err := tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerAlwaysOff})
+	require.NoError(t, err)
+	assert.Equal(t, sdktrace.Drop, shouldSample(off, parentContext(true), "span"))
+}
+
+func TestNewSamplerParentBased(t *testing.T) {
+	for _, opts := range []tracing.SamplerOptions{
+		{},
+		{Type: tracing.SamplerParentBasedAlwaysOn},
+		{Type: tracing.SamplerParentBasedAlwaysOff},
+		{Type: tracing.SamplerParentBasedTraceIDRatio, Ratio: 0.5},
+		{Type: tracing.SamplerParentBasedRateLimiting, SpansPerSecond: 1},
+	} {
+		s, err := tracing.NewSampler(opts)
+		require.NoError(t, err)
+
+		assert.Equal(t, sdktrace.RecordAndSample, shouldSample(s, parentContext(true), "span"),
+			"%s should sample when the parent is sampled", opts.Type)
+		assert.Equal(t, sdktrace.Drop, shouldSample(s, parentContext(false), "span"),
+			"%s should drop when the parent is not sampled", opts.Type)
+	}
+
+	root, err := tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerParentBasedAlwaysOff})
+	require.NoError(t, err)
+	assert.Equal(t, sdktrace.Drop, shouldSample(root, context.Background(), "span"))
+
+	root, err = tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerParentBasedTraceIDRatio, Ratio: 1})
+	require.NoError(t, err)
+	assert.Equal(t, sdktrace.RecordAndSample, shouldSample(root, context.Background(), "span"))
+}
+
+func TestNewSamplerTraceIDRatioIgnoresParent(t *testing.T) {
+	// testTraceID is above the bound of a 0.001 ratio
+	s, err := tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerTraceIDRatio, Ratio: 0.001})
+	require.NoError(t, err)
+	assert.Equal(t, sdktrace.Drop, shouldSample(s, parentContext(true), "span"))
+}
+
+func TestNewSamplerRateLimiting(t *testing.T) {
+	s, err := tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerRateLimiting, SpansPerSecond: 10})
+	require.NoError(t, err)
+
+	sampled := 0
+	for i := 0; i < 50; i++ {
+		if shouldSample(s, context.Background(), "span") == sdktrace.RecordAndSample {
+			sampled++
+		}
+	}
+	assert.Equal(t, 10, sampled, "a burst should be limited to the spans per second")
+
+	time.Sleep(250 * time.Millisecond)
+	assert.Equal(t, sdktrace.RecordAndSample, shouldSample(s, context.Background(), "span"),
+		"tokens should be refilled as time passes")
+}
+
+func TestNewSamplerRules(t *testing.T) {
+	s, err := tracing.NewSampler(tracing.SamplerOptions{
+		Type: tracing.SamplerAlwaysOff,
+		Rules: []tracing.SamplingRule{
+			{SpanName: "GET /health", Sample: false},
+			{Attributes: []attribute.KeyValue{attribute.Bool("error", true)}, Sample: true},
+			{SpanName: "checkout", Attributes: []attribute.KeyValue{attribute.String("tier", "gold")}, Sample: true},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, sdktrace.Drop, shouldSample(s, context.Background(), "GET /health", attribute.Bool("error", true)),
+		"the first matching rule should win")
+	assert.Equal(t, sdktrace.RecordAndSample, shouldSample(s, context.Background(), "GET /orders", attribute.Bool("error", true)))
+	assert.Equal(t, sdktrace.RecordAndSample, shouldSample(s, context.Background(), "checkout", attribute.String("tier", "gold")))
+	assert.Equal(t, sdktrace.Drop, shouldSample(s, context.Background(), "checkout", attribute.String("tier", "silver")),
+		"spans that match no rule should use the fallback sampler")
+	assert.Equal(t, sdktrace.Drop, shouldSample(s, context.Background(), "GET /orders"))
+}
+
+func TestNewSamplerRulesFollowParent(t *testing.T) {
+	rules := []tracing.SamplingRule{
+		{SpanName: "GET /health", Sample: false},
+		{SpanName: "checkout", Sample: true},
+	}
+
+	s, err := tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerParentBasedAlwaysOff, Rules: rules})
+	require.NoError(t, err)
+	assert.Equal(t, sdktrace.Drop, shouldSample(s, context.Background(), "GET /health"))
+	assert.Equal(t, sdktrace.RecordAndSample, shouldSample(s, context.Background(), "checkout"))
+	assert.Equal(t, sdktrace.RecordAndSample, shouldSample(s, parentContext(true), "GET /health"),
+		"a span with a sampled parent should be sampled even if a rule drops it")
+	assert.Equal(t, sdktrace.Drop, shouldSample(s, parentContext(false), "checkout"),
+		"a span with an unsampled parent should be dropped even if a rule samples it")
+
+	s, err = tracing.NewSampler(tracing.SamplerOptions{Type: tracing.SamplerAlwaysOn, Rules: rules})
+	require.NoError(t, err)
+	assert.Equal(t, sdktrace.Drop, shouldSample(s, parentContext(true), "GET /health"),
+		"the rules should ignore the parent when the type does")
+}
+
+func TestInitializeWithOptionsSampler(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	err := tracing.InitializeWithOptions(tracing.Options{
+		Exporter:    exporter,
+		ServiceName: serviceName,
+		Sampler: tracing.SamplerOptions{
+			Rules: []tracing.SamplingRule{{SpanName: "health", Sample: false}},
+		},
+	})
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	_, health := tracing.Start(context.Background(), "health", oteltrace.SpanKindServer)
+	health.End()
+
+	_, remote := tracing.Start(parentContext(false), "unsampled-parent", oteltrace.SpanKindServer)
+	remote.End()
+
+	_, root := tracing.Start(context.Background(), "root", oteltrace.SpanKindServer)
+	root.End()
+
+	require.NoError(t, tracing.ForceFlush(context.Background()))
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "root", spans[0].Name)
+}
+
+func TestInitializeWithOptionsKeepErrors(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	err := tracing.InitializeWithOptions(tracing.Options{
+		Exporter:    exporter,
+		ServiceName: serviceName,
+		Sampler: tracing.SamplerOptions{
+			Type:       tracing.SamplerAlwaysOff,
+			KeepErrors: true,
+		},
+	})
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	_, ok := tracing.Start(context.Background(), "ok", oteltrace.SpanKindServer)
+	ok.SetStatus(codes.Ok, "")
+	ok.End()
+
+	_, failed := tracing.Start(context.Background(), "failed", oteltrace.SpanKindServer)
+	failed.SetStatus(codes.Error, "boom")
+	failed.End()
+
+	require.NoError(t, tracing.ForceFlush(context.Background()))
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "failed", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
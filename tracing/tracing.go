@@ -2,7 +2,11 @@ package tracing
 
 import (
 	"context"
-	"github.com/pkg/errors"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/twistingmercury/telemetry/v2/redact"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
@@ -10,22 +14,36 @@ import (
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
 	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 const (
 	SampleRateDefault = 1.0
 )
 
+// previousShutdownTimeout bounds the shutdown of the provider replaced by a new call to [InitializeWithOptions], so an
+// unreachable exporter cannot block the initialization.
+const previousShutdownTimeout = 5 * time.Second
+
 var (
-	tracer      oteltrace.Tracer
-	propagator  propagation.TextMapPropagator
-	commonAttrs []attribute.KeyValue
-	svcName     string
-	svcVersion  string
-	env         string
+	mu         sync.RWMutex
+	provider   *sdktrace.TracerProvider
+	tracer     oteltrace.Tracer
+	propagator propagation.TextMapPropagator
+	svcName    string
+	svcVersion string
+	env        string
 )
 
+// Tracer returns the [oteltrace.Tracer] created by [Initialize]. Before tracing is initialized it returns a tracer
+// from the global tracer provider.
 func Tracer() oteltrace.Tracer {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if tracer == nil {
+		return otel.Tracer("")
+	}
 	return tracer
 }
 
@@ -40,62 +58,197 @@ func Initialize(exporter sdktrace.SpanExporter, serviceName, serviceVersion, env
 }
 
 // InitializeWithSampleRate initializes the OpenTelemetry tracing, and sets the sample rate to the value passed by the sampleRate arg.
+// The sample rate is applied to every trace regardless of the decision of the parent; use [InitializeWithOptions]
+// to select a different sampler.
 func InitializeWithSampleRate(exporter sdktrace.SpanExporter, sampleRate float64, serviceName, serviceVersion, environment string) (err error) {
-	if exporter == nil {
-		return errors.New("trace exporter is required")
-	}
-
 	if sampleRate == 0 {
 		return errors.New("sample-rate must be a floating point value between 0.1 and 1.0")
 	}
 
-	svcName = serviceName
-	svcVersion = serviceVersion
-	env = environment
+	return InitializeWithOptions(Options{
+		Exporter:       exporter,
+		ServiceName:    serviceName,
+		ServiceVersion: serviceVersion,
+		Environment:    environment,
+		Sampler: SamplerOptions{
+			Type:  SamplerTraceIDRatio,
+			Ratio: sampleRate,
+		},
+	})
+}
+
+// Options are the settings used by [InitializeWithOptions] to initialize tracing.
+type Options struct {
+	// Exporter is the exporter spans are sent to. It is required unless ExporterOptions.Type is set.
+	Exporter sdktrace.SpanExporter
+	// ExporterOptions selects and configures the exporter created with [NewExporter] when Exporter is nil.
+	ExporterOptions ExporterOptions
+	// ServiceName is set as the `service.name` resource attribute.
+	ServiceName string
+	// ServiceVersion is set as the `service.version` resource attribute.
+	ServiceVersion string
+	// Environment is set as the `deployment.environment` resource attribute.
+	Environment string
+	// ResourceAttributes are additional attributes set on the resource, such as `k8s.pod.name`. The service name,
+	// version and environment take precedence over the same keys in ResourceAttributes.
+	ResourceAttributes []attribute.KeyValue
+	// Sampler selects which spans are sampled. The default follows the decision of the parent span and samples
+	// every root span.
+	Sampler SamplerOptions
+	// Propagators are the formats used to extract and inject the trace context. The default is
+	// [DefaultPropagators].
+	Propagators []Propagator
+	// Redaction, when set, redacts the sensitive values of the span and event attributes before the spans are
+	// exported. See [NewRedactingProcessor].
+	Redaction *redact.Policy
+}
+
+// InitializeWithOptions initializes the OpenTelemetry tracing with the provided options. The tracer provider of a
+// previous call is shut down once the new one is in place. The exporter is owned by tracing from then on: it is shut
+// down by [Shutdown], or before InitializeWithOptions returns an error.
+func InitializeWithOptions(opts Options) (err error) {
+	if opts.Exporter == nil {
+		if opts.ExporterOptions.Type == "" {
+			return errors.New("trace exporter is required")
+		}
+		if opts.Exporter, err = NewExporter(context.Background(), opts.ExporterOptions); err != nil {
+			return
+		}
+	}
+	defer func() {
+		if err != nil {
+			_ = opts.Exporter.Shutdown(context.Background())
+		}
+	}()
+
+	sampler, err := NewSampler(opts.Sampler)
+	if err != nil {
+		return
+	}
 
-	commonAttrs = []attribute.KeyValue{
-		semconv.ServiceNameKey.String(svcName),
-		semconv.ServiceVersionKey.String(svcVersion),
-		semconv.DeploymentEnvironmentKey.String(env),
+	prop, err := NewPropagator(opts.Propagators...)
+	if err != nil {
+		return
 	}
 
+	// The service attributes are set once on the resource, so they are attached to every exported span
+	// without being copied onto each span's own attributes.
 	res, err := resource.New(
 		context.Background(),
-		resource.WithAttributes(commonAttrs...))
+		resource.WithAttributes(opts.ResourceAttributes...),
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String(opts.ServiceName),
+			semconv.ServiceVersionKey.String(opts.ServiceVersion),
+			semconv.DeploymentEnvironmentKey.String(opts.Environment),
+		))
 	if err != nil {
 		return
 	}
 
-	bsp := sdktrace.NewBatchSpanProcessor(exporter)
+	var processor sdktrace.SpanProcessor = sdktrace.NewBatchSpanProcessor(opts.Exporter)
+	if opts.Redaction != nil {
+		processor = NewRedactingProcessor(processor, opts.Redaction)
+	}
+	if opts.Sampler.KeepErrors {
+		sampler = recordingSampler{Sampler: sampler}
+		processor = errorSpanProcessor{SpanProcessor: processor}
+	}
+
 	traceProvider := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.TraceIDRatioBased(sampleRate)),
+		sdktrace.WithSampler(sampler),
 		sdktrace.WithResource(res),
-		sdktrace.WithSpanProcessor(bsp),
+		sdktrace.WithSpanProcessor(processor),
 	)
 
 	otel.SetTracerProvider(traceProvider)
-	propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
-	otel.SetTextMapPropagator(propagator)
+	otel.SetTextMapPropagator(prop)
+
+	mu.Lock()
+	previous := provider
+	svcName = opts.ServiceName
+	svcVersion = opts.ServiceVersion
+	env = opts.Environment
+	provider = traceProvider
+	propagator = prop
+	tracer = traceProvider.Tracer(opts.ServiceName, oteltrace.WithInstrumentationVersion(opts.ServiceVersion))
+	mu.Unlock()
+
+	// The spans of the previous provider are flushed to its exporter; its errors are not errors of the new one.
+	if previous != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), previousShutdownTimeout)
+		defer cancel()
+		_ = previous.Shutdown(ctx)
+	}
+	return
+}
 
-	tp := otel.GetTracerProvider()
+// ForceFlush exports all spans buffered by the batch span processor. It honours the deadline of ctx and
+// returns any error reported by the exporter. Calling ForceFlush before [Initialize] or after [Shutdown] is a no-op.
+func ForceFlush(ctx context.Context) error {
+	mu.RLock()
+	tp := provider
+	mu.RUnlock()
 
-	tracer = tp.Tracer(serviceName, oteltrace.WithInstrumentationVersion(serviceVersion))
+	if tp == nil {
+		return nil
+	}
+	return tp.ForceFlush(ctx)
+}
 
-	return
+// Shutdown flushes any buffered spans and then shuts down the tracer provider and its exporter. It honours the
+// deadline of ctx and returns any errors reported while flushing or shutting down. It is safe to call more than once;
+// calls after the first are no-ops. Once shut down, [Tracer] and the context propagation use the global tracer
+// provider and propagator, as they do before tracing is initialized. The global tracer provider, if it is still the one
+// set by [Initialize], is replaced with a no-op provider.
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	tp := provider
+	provider = nil
+	tracer = nil
+	propagator = nil
+	if tp != nil && otel.GetTracerProvider() == oteltrace.TracerProvider(tp) {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	}
+	mu.Unlock()
+
+	if tp == nil {
+		return nil
+	}
+	return errors.Join(tp.ForceFlush(ctx), tp.Shutdown(ctx))
 }
 
-// ExtractContext returns the [context.Context] for OTel tracing that may be passed
+// ExtractContext returns the [context.Context] for OTel tracing that may be passed. The trace context is extracted
+// using the propagators configured by [Options.Propagators].
 func ExtractContext(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
-	return propagator.Extract(ctx, carrier)
+	return textMapPropagator().Extract(ctx, carrier)
 }
 
-func Start(ctx context.Context, name string, kind oteltrace.SpanKind, attribs ...attribute.KeyValue) (spanCtx context.Context, span oteltrace.Span) {
-	commonAttrs = append(commonAttrs, attribs...)
+// InjectContext writes the trace context of the span in ctx into the carrier, such as the headers of an outgoing
+// request or message, using the propagators configured by [Options.Propagators].
+func InjectContext(ctx context.Context, carrier propagation.TextMapCarrier) {
+	textMapPropagator().Inject(ctx, carrier)
+}
+
+// textMapPropagator returns the configured propagator, or the global propagator if tracing is not initialized.
+func textMapPropagator() propagation.TextMapPropagator {
+	mu.RLock()
+	p := propagator
+	mu.RUnlock()
 
+	if p == nil {
+		return otel.GetTextMapPropagator()
+	}
+	return p
+}
+
+// Start creates a new span of the given kind. The attribs are applied only to the span being started; the service
+// name, version and environment are carried by the resource and do not need to be passed. It is safe to call Start
+// from multiple goroutines.
+func Start(ctx context.Context, name string, kind oteltrace.SpanKind, attribs ...attribute.KeyValue) (spanCtx context.Context, span oteltrace.Span) {
 	spanCtx, span = Tracer().Start(
 		ctx,
 		name,
 		oteltrace.WithSpanKind(kind),
-		oteltrace.WithAttributes(commonAttrs...))
+		oteltrace.WithAttributes(attribs...))
 	return
 }
@@ -2,13 +2,21 @@ package tracing_test
 
 import (
 	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/twistingmercury/telemetry/v2/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 const (
@@ -17,8 +25,54 @@ const (
 	environment    = "unit-test"
 )
 
-type mockExporter struct {
-	sdktrace.SpanExporter
+type mockExporter struct{}
+
+func (e *mockExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	return nil
+}
+
+func (e *mockExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+type failingExporter struct {
+	err error
+}
+
+func (e *failingExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	return e.err
+}
+
+func (e *failingExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+type recordingExporter struct {
+	names    []string
+	shutdown bool
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	for _, s := range spans {
+		e.names = append(e.names, s.Name())
+	}
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error {
+	e.shutdown = true
+	return nil
+}
+
+type blockingExporter struct{}
+
+func (e *blockingExporter) ExportSpans(ctx context.Context, _ []sdktrace.ReadOnlySpan) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (e *blockingExporter) Shutdown(context.Context) error {
+	return nil
 }
 
 func TestInitialize(t *testing.T) {
@@ -35,6 +89,39 @@ func TestInitialize(t *testing.T) {
 
 }
 
+func TestInitializeShutsDownPreviousProvider(t *testing.T) {
+	first := &recordingExporter{}
+	require.NoError(t, tracing.Initialize(first, serviceName, serviceVersion, environment))
+	_, span := tracing.Start(context.Background(), "first", oteltrace.SpanKindInternal)
+	span.End()
+
+	second := &recordingExporter{}
+	require.NoError(t, tracing.Initialize(second, serviceName, serviceVersion, environment))
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	assert.True(t, first.shutdown, "the previous exporter should be shut down")
+	assert.Equal(t, []string{"first"}, first.names, "the spans of the previous provider should be flushed")
+	assert.False(t, second.shutdown)
+}
+
+func TestInitializeWithOptionsErrorShutsDownExporter(t *testing.T) {
+	exporter := &recordingExporter{}
+	err := tracing.InitializeWithOptions(tracing.Options{
+		Exporter: exporter,
+		Sampler:  tracing.SamplerOptions{Type: "unknown"},
+	})
+	require.Error(t, err)
+	assert.True(t, exporter.shutdown, "the exporter should be shut down when the sampler is not valid")
+
+	exporter = &recordingExporter{}
+	err = tracing.InitializeWithOptions(tracing.Options{
+		Exporter:    exporter,
+		Propagators: []tracing.Propagator{"unknown"},
+	})
+	require.Error(t, err)
+	assert.True(t, exporter.shutdown, "the exporter should be shut down when a propagator is not valid")
+}
+
 func TestInitializeWithNilExporter(t *testing.T) {
 	err := tracing.Initialize(nil, serviceName, serviceVersion, environment)
 	assert.Error(t, err, "InitializeWithSampleRate should return an error when exporter is nil")
@@ -79,3 +166,120 @@ func TestStart(t *testing.T) {
 	assert.NotEqual(t, oteltrace.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, span.SpanContext().TraceID(), "trace ID should not be empty")
 	assert.NotEqual(t, oteltrace.SpanID{0, 0, 0, 0, 0, 0, 0, 0}, span.SpanContext().SpanID(), "span ID should not be empty")
 }
+
+func TestForceFlush(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	err := tracing.Initialize(exporter, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	_, span := tracing.Start(context.Background(), "flushed-span", oteltrace.SpanKindInternal)
+	span.End()
+	assert.Empty(t, exporter.GetSpans(), "span should still be buffered before flushing")
+
+	require.NoError(t, tracing.ForceFlush(context.Background()))
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "flushed-span", spans[0].Name)
+}
+
+func TestForceFlushReportsExporterError(t *testing.T) {
+	expected := errors.New("export failed")
+	err := tracing.Initialize(&failingExporter{err: expected}, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	_, span := tracing.Start(context.Background(), "failing-span", oteltrace.SpanKindInternal)
+	span.End()
+
+	assert.ErrorIs(t, tracing.ForceFlush(context.Background()), expected)
+}
+
+func TestForceFlushHonoursDeadline(t *testing.T) {
+	err := tracing.Initialize(&blockingExporter{}, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	_, span := tracing.Start(context.Background(), "blocked-span", oteltrace.SpanKindInternal)
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, tracing.ForceFlush(ctx), context.DeadlineExceeded)
+}
+
+func TestShutdown(t *testing.T) {
+	exporter := &recordingExporter{}
+	err := tracing.Initialize(exporter, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+
+	_, span := tracing.Start(context.Background(), "shutdown-span", oteltrace.SpanKindInternal)
+	span.End()
+
+	require.NoError(t, tracing.Shutdown(context.Background()))
+	assert.Equal(t, []string{"shutdown-span"}, exporter.names, "buffered spans should be exported on shutdown")
+	assert.IsType(t, noop.TracerProvider{}, otel.GetTracerProvider(), "the global provider should not be the shut down one")
+	_, span = tracing.Start(context.Background(), "after-shutdown", oteltrace.SpanKindInternal)
+	assert.False(t, span.IsRecording())
+
+	assert.NoError(t, tracing.Shutdown(context.Background()), "Shutdown should be safe to call more than once")
+	assert.NoError(t, tracing.ForceFlush(context.Background()), "ForceFlush after Shutdown should be a no-op")
+}
+
+func TestShutdownBeforeInitialize(t *testing.T) {
+	assert.NoError(t, tracing.Shutdown(context.Background()))
+}
+
+func TestStartAttributesArePerSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	err := tracing.Initialize(exporter, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	_, first := tracing.Start(context.Background(), "first", oteltrace.SpanKindInternal, attribute.String("request", "1"))
+	first.End()
+	_, second := tracing.Start(context.Background(), "second", oteltrace.SpanKindInternal)
+	second.End()
+
+	require.NoError(t, tracing.ForceFlush(context.Background()))
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, []attribute.KeyValue{attribute.String("request", "1")}, spans[0].Attributes)
+	assert.Empty(t, spans[1].Attributes, "attributes from a previous call must not leak onto later spans")
+
+	for _, span := range spans {
+		svc, ok := span.Resource.Set().Value("service.name")
+		assert.True(t, ok, "service.name should be set on the resource")
+		assert.Equal(t, serviceName, svc.AsString())
+	}
+}
+
+func TestStartConcurrently(t *testing.T) {
+	const goroutines = 500
+
+	exporter := tracetest.NewInMemoryExporter()
+	err := tracing.Initialize(exporter, serviceName, serviceVersion, environment)
+	require.NoError(t, err)
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(i int) {
+			defer wg.Done()
+			_, span := tracing.Start(context.Background(), strconv.Itoa(i), oteltrace.SpanKindServer, attribute.Int("goroutine", i))
+			span.End()
+		}(i)
+	}
+	wg.Wait()
+
+	require.NoError(t, tracing.ForceFlush(context.Background()))
+	spans := exporter.GetSpans()
+	require.Len(t, spans, goroutines)
+
+	for _, span := range spans {
+		require.Len(t, span.Attributes, 1, "span %s should only carry its own attribute", span.Name)
+		assert.Equal(t, span.Name, strconv.Itoa(int(span.Attributes[0].Value.AsInt64())))
+	}
+}